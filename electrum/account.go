@@ -0,0 +1,262 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// DefaultGapLimit is the number of consecutive unused addresses after which an account
+// scan stops, as recommended by BIP44.
+const DefaultGapLimit = 20
+
+var (
+	// ErrUnknownKeyVersion throws an error if an extended key version is not a known account version.
+	ErrUnknownKeyVersion = errors.New("unknown extended key version")
+)
+
+var accountKeyVersions = []struct {
+	version  []byte
+	addrType AddressType
+	mainnet  bool
+}{
+	{[]byte{0x04, 0x88, 0xb2, 0x1e}, AddressP2PKH, true},       // xpub
+	{[]byte{0x04, 0x9d, 0x7c, 0xb2}, AddressP2SHP2WPKH, true},  // ypub
+	{[]byte{0x04, 0xb2, 0x47, 0x46}, AddressP2WPKH, true},      // zpub
+	{[]byte{0x04, 0x35, 0x87, 0xcf}, AddressP2PKH, false},      // tpub
+	{[]byte{0x04, 0x4a, 0x52, 0x62}, AddressP2SHP2WPKH, false}, // upub
+	{[]byte{0x04, 0x5f, 0x1c, 0xf6}, AddressP2WPKH, false},     // vpub
+}
+
+// ParseAccountKey decodes an account extended public key and returns the address type its
+// version implies: xpub and tpub for BIP44, ypub and upub for BIP49, zpub and vpub for BIP84.
+// BIP86 accounts share the xpub version, and must be scanned as AddressP2TR explicitly.
+func ParseAccountKey(key string) (*hdkeychain.ExtendedKey, AddressType, error) {
+	account, err := hdkeychain.NewKeyFromString(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, v := range accountKeyVersions {
+		if bytes.Equal(account.Version(), v.version) {
+			return account, v.addrType, nil
+		}
+	}
+
+	return nil, 0, ErrUnknownKeyVersion
+}
+
+// accountParams returns the network the addresses of account are encoded for. The version
+// of the key tells a main network key from a test network one, the network of the client
+// telling the test networks apart. Keys of unknown versions use the network of the client.
+func (s *Client) accountParams(account *hdkeychain.ExtendedKey) *chaincfg.Params {
+	params := s.ChainParams()
+	for _, v := range accountKeyVersions {
+		if !bytes.Equal(account.Version(), v.version) {
+			continue
+		}
+
+		switch {
+		case v.mainnet == (params.Net == wire.MainNet):
+			return params
+		case v.mainnet:
+			return &chaincfg.MainNetParams
+		default:
+			return &chaincfg.TestNet3Params
+		}
+	}
+
+	return params
+}
+
+// ScannedAddress is an address of an account that has been used on chain.
+type ScannedAddress struct {
+	Address    btcutil.Address
+	Scripthash string
+	Change     bool
+	Index      uint32
+	History    []*GetMempoolResult
+	Balance    GetBalanceResult
+	Unspent    []*ListUnspentResult
+}
+
+// AccountScanResult holds the used addresses and funds of an account.
+type AccountScanResult struct {
+	Addresses []*ScannedAddress
+	Balance   GetBalanceResult
+	Unspent   []*ListUnspentResult
+
+	// NextReceiveIndex and NextChangeIndex are the first indexes following the last used
+	// address of the receive and change chains.
+	NextReceiveIndex uint32
+	NextChangeIndex  uint32
+}
+
+// ScanExtendedKey scans the account of the extended public key, with the address type its
+// version implies. See ScanAccount().
+func (s *Client) ScanExtendedKey(ctx context.Context, key string, gapLimit uint32) (*AccountScanResult, error) {
+	account, addrType, err := ParseAccountKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ScanAccount(ctx, account, addrType, gapLimit)
+}
+
+// ScanAccount derives the receive and change addresses of an account extended public key and
+// queries their history in batches, until gapLimit consecutive addresses of each chain are
+// unused. The balances and UTXOs of every used address are then fetched, in batches of at
+// most gapLimit addresses. Addresses are encoded for the network implied by the key version,
+// and the rare indexes without a valid child key are skipped.
+func (s *Client) ScanAccount(ctx context.Context, account *hdkeychain.ExtendedKey, addrType AddressType,
+	gapLimit uint32) (*AccountScanResult, error) {
+
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	params := s.accountParams(account)
+
+	result := &AccountScanResult{}
+	for chain := uint32(0); chain < 2; chain++ {
+		branch, err := account.Derive(chain)
+		if err != nil {
+			return nil, err
+		}
+
+		used, next, err := s.scanChain(ctx, branch, addrType, params, chain == 1, gapLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Addresses = append(result.Addresses, used...)
+		if chain == 0 {
+			result.NextReceiveIndex = next
+		} else {
+			result.NextChangeIndex = next
+		}
+	}
+
+	for start := 0; start < len(result.Addresses); start += int(gapLimit) {
+		end := start + int(gapLimit)
+		if end > len(result.Addresses) {
+			end = len(result.Addresses)
+		}
+
+		err := s.fetchFunds(ctx, result.Addresses[start:end])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, addr := range result.Addresses {
+		result.Balance.Confirmed += addr.Balance.Confirmed
+		result.Balance.Unconfirmed += addr.Balance.Unconfirmed
+		result.Unspent = append(result.Unspent, addr.Unspent...)
+	}
+
+	return result, nil
+}
+
+// fetchFunds queries the balance and UTXOs of addrs in a single batch.
+func (s *Client) fetchFunds(ctx context.Context, addrs []*ScannedAddress) error {
+	batch := s.Batch()
+	balances := make([]*BalanceCall, len(addrs))
+	unspents := make([]*UnspentCall, len(addrs))
+	for i, addr := range addrs {
+		balances[i] = batch.GetBalance(addr.Scripthash)
+		unspents[i] = batch.ListUnspent(addr.Scripthash)
+	}
+
+	err := batch.Send(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, addr := range addrs {
+		addr.Balance, err = balances[i].Result()
+		if err != nil {
+			return err
+		}
+
+		addr.Unspent, err = unspents[i].Result()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanChain returns the used addresses of a derivation chain, and the index following the
+// last used one.
+func (s *Client) scanChain(ctx context.Context, branch *hdkeychain.ExtendedKey, addrType AddressType,
+	params *chaincfg.Params, change bool, gapLimit uint32) ([]*ScannedAddress, uint32, error) {
+
+	var used []*ScannedAddress
+	next := uint32(0)
+	for index := uint32(0); index < next+gapLimit; {
+		end := next + gapLimit
+
+		batch := s.Batch()
+		addrs := make([]*ScannedAddress, 0, end-index)
+		calls := make([]*HistoryCall, 0, end-index)
+		for ; index < end; index++ {
+			addr, err := deriveScannedAddress(branch, addrType, params, change, index)
+			if errors.Is(err, hdkeychain.ErrInvalidChild) {
+				continue
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+
+			addrs = append(addrs, addr)
+			calls = append(calls, batch.GetHistory(addr.Scripthash))
+		}
+
+		err := batch.Send(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for i, addr := range addrs {
+			addr.History, err = calls[i].Result()
+			if err != nil {
+				return nil, 0, err
+			}
+
+			if len(addr.History) > 0 {
+				used = append(used, addr)
+				next = addr.Index + 1
+			}
+		}
+	}
+
+	return used, next, nil
+}
+
+func deriveScannedAddress(branch *hdkeychain.ExtendedKey, addrType AddressType, params *chaincfg.Params,
+	change bool, index uint32) (*ScannedAddress, error) {
+
+	address, err := DeriveAddress(branch, addrType, params, index)
+	if err != nil {
+		return nil, err
+	}
+
+	scripthash, err := BtcutilAddressToElectrumScriptHash(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScannedAddress{
+		Address:    address,
+		Scripthash: scripthash,
+		Change:     change,
+		Index:      index,
+	}, nil
+}
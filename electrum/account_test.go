@@ -0,0 +1,120 @@
+package electrum_test
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// balanceBatchTransport counts the messages querying balances sent through it.
+type balanceBatchTransport struct {
+	electrum.Transport
+	batches *int32
+}
+
+func (t *balanceBatchTransport) SendMessage(body []byte) error {
+	if bytes.Contains(body, []byte("blockchain.scripthash.get_balance")) {
+		atomic.AddInt32(t.batches, 1)
+	}
+
+	return t.Transport.SendMessage(body)
+}
+
+func TestScanAccount(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	var batches int32
+	dialer := func(ctx context.Context) (electrum.Transport, error) {
+		transport, err := server.Transport()
+		if err != nil {
+			return nil, err
+		}
+
+		return &balanceBatchTransport{Transport: transport, batches: &batches}, nil
+	}
+
+	client, err := electrum.NewClient(context.Background(), "", electrum.WithTransportDialer(dialer),
+		electrum.WithChainParams(chain.Params()))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{1}, 32), chain.Params())
+	require.NoError(t, err)
+	account, err := master.Neuter()
+	require.NoError(t, err)
+
+	fund := func(change, index uint32, amount btcutil.Amount) {
+		address, err := electrum.DeriveAddress(account, electrum.AddressP2WPKH, chain.Params(), change, index)
+		require.NoError(t, err)
+		script, err := txscript.PayToAddrScript(address)
+		require.NoError(t, err)
+		_, err = chain.Fund(script, amount)
+		require.NoError(t, err)
+	}
+
+	// With a gap limit of 2, the receive address 5 follows too many unused addresses.
+	fund(0, 0, 1000)
+	fund(0, 2, 2000)
+	fund(0, 5, 4000)
+	fund(1, 0, 100)
+	fund(1, 1, 200)
+	chain.Mine(1)
+
+	result, err := client.ScanAccount(context.Background(), account, electrum.AddressP2WPKH, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(3), result.NextReceiveIndex)
+	assert.Equal(t, uint32(2), result.NextChangeIndex)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 3300}, result.Balance)
+	assert.Len(t, result.Unspent, 4)
+
+	require.Len(t, result.Addresses, 4)
+	for _, addr := range result.Addresses {
+		assert.True(t, addr.Address.IsForNet(chain.Params()), addr.Address.String())
+		assert.Len(t, addr.History, 1)
+	}
+
+	// The funds of the 4 used addresses are fetched 2 addresses at a time.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&batches))
+}
+
+func TestScanAccountKeyNetwork(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(context.Background(), electrum.WithChainParams(chain.Params()))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	// A main network key scanned by a client of the regression test network.
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{1}, 32), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	account, err := master.Neuter()
+	require.NoError(t, err)
+
+	address, err := electrum.DeriveAddress(account, electrum.AddressP2PKH, &chaincfg.MainNetParams, 0, 0)
+	require.NoError(t, err)
+	script, err := txscript.PayToAddrScript(address)
+	require.NoError(t, err)
+	_, err = chain.Fund(script, 1000)
+	require.NoError(t, err)
+
+	result, err := client.ScanExtendedKey(context.Background(), account.String(), 2)
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	assert.Equal(t, address.String(), result.Addresses[0].Address.String())
+	assert.True(t, result.Addresses[0].Address.IsForNet(&chaincfg.MainNetParams))
+}
@@ -3,29 +3,196 @@ package electrum
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 )
 
+var (
+	// ErrUnknownAddressType throws an error if an address type is not supported.
+	ErrUnknownAddressType = errors.New("unknown address type")
+
+	// ErrWrongNetwork throws an error if an address does not belong to the expected network.
+	ErrWrongNetwork = errors.New("address is not for the expected network")
+)
+
+// AddressType identifies the script an address pays to.
+type AddressType int
+
+const (
+	// AddressP2PKH pays to a public key hash, as derived by BIP44 accounts.
+	AddressP2PKH AddressType = iota
+
+	// AddressP2SHP2WPKH pays to a witness public key hash nested in a script hash, as derived
+	// by BIP49 accounts.
+	AddressP2SHP2WPKH
+
+	// AddressP2WPKH pays to a witness public key hash, as derived by BIP84 accounts.
+	AddressP2WPKH
+
+	// AddressP2TR pays to a taproot output key without script path, as derived by BIP86 accounts.
+	AddressP2TR
+)
+
+func (t AddressType) String() string {
+	switch t {
+	case AddressP2PKH:
+		return "p2pkh"
+	case AddressP2SHP2WPKH:
+		return "p2sh-p2wpkh"
+	case AddressP2WPKH:
+		return "p2wpkh"
+	case AddressP2TR:
+		return "p2tr"
+	}
+
+	return "unknown"
+}
+
 // AddressToElectrumScriptHash converts valid bitcoin address to electrum scriptHash sha256 encoded, reversed and encoded in hex
+// The address is decoded for the bitcoin main network, unless other network parameters are given.
 // https://electrumx.readthedocs.io/en/latest/protocol-basics.html#script-hashes
-func AddressToElectrumScriptHash(addressStr string) (string, error) {
-	address, err := btcutil.DecodeAddress(addressStr, &chaincfg.MainNetParams)
+func AddressToElectrumScriptHash(addressStr string, params ...*chaincfg.Params) (string, error) {
+	net := &chaincfg.MainNetParams
+	if len(params) > 0 && params[0] != nil {
+		net = params[0]
+	}
+
+	address, err := btcutil.DecodeAddress(addressStr, net)
 	if err != nil {
 		return "", err
 	}
+	if !address.IsForNet(net) {
+		return "", ErrWrongNetwork
+	}
+
+	return BtcutilAddressToElectrumScriptHash(address)
+}
+
+// BtcutilAddressToElectrumScriptHash converts a decoded address to electrum scriptHash.
+func BtcutilAddressToElectrumScriptHash(address btcutil.Address) (string, error) {
 	script, err := txscript.PayToAddrScript(address)
 	if err != nil {
 		return "", err
 	}
 
+	return ScriptToElectrumScriptHash(script), nil
+}
+
+// ScriptToElectrumScriptHash converts a raw output script (scriptPubKey) to electrum scriptHash.
+func ScriptToElectrumScriptHash(script []byte) string {
 	hashSum := sha256.Sum256(script)
 
 	for i, j := 0, len(hashSum)-1; i < j; i, j = i+1, j-1 {
 		hashSum[i], hashSum[j] = hashSum[j], hashSum[i]
 	}
 
-	return hex.EncodeToString(hashSum[:]), nil
+	return hex.EncodeToString(hashSum[:])
+}
+
+// PubKeyToAddress returns the address of type addrType paying to pubKey on the network
+// described by params.
+func PubKeyToAddress(pubKey *btcec.PublicKey, addrType AddressType, params *chaincfg.Params) (btcutil.Address, error) {
+	switch addrType {
+	case AddressP2PKH:
+		return btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+
+	case AddressP2SHP2WPKH:
+		witness, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+		if err != nil {
+			return nil, err
+		}
+
+		script, err := txscript.PayToAddrScript(witness)
+		if err != nil {
+			return nil, err
+		}
+
+		return btcutil.NewAddressScriptHash(script, params)
+
+	case AddressP2WPKH:
+		return btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+
+	case AddressP2TR:
+		outputKey := txscript.ComputeTaprootKeyNoScript(pubKey)
+
+		return btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
+	}
+
+	return nil, ErrUnknownAddressType
+}
+
+// DeriveAddress derives the public child of key at path and returns its address of type
+// addrType on the network described by params.
+func DeriveAddress(key *hdkeychain.ExtendedKey, addrType AddressType, params *chaincfg.Params, path ...uint32) (btcutil.Address, error) {
+	var err error
+	for _, index := range path {
+		key, err = key.Derive(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return PubKeyToAddress(pubKey, addrType, params)
+}
+
+// ExtendedKeyToElectrumScriptHash derives the public child of the serialized extended key at
+// path, for instance 0/5 for the sixth receive address of an account xpub, and converts its
+// address of type addrType to electrum scriptHash.
+func ExtendedKeyToElectrumScriptHash(extendedKey string, addrType AddressType, path ...uint32) (string, error) {
+	key, err := hdkeychain.NewKeyFromString(extendedKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Script hashes do not depend on the network, only the address encoding does.
+	address, err := DeriveAddress(key, addrType, &chaincfg.MainNetParams, path...)
+	if err != nil {
+		return "", err
+	}
+
+	return BtcutilAddressToElectrumScriptHash(address)
+}
+
+// SetChainParams sets the network addresses are decoded for by this client. Unless trusted
+// checkpoints have been set with SetCheckpoints(), the default checkpoints of the network
+// are used, replacing the ones of the previous network.
+func (s *Client) SetChainParams(params *chaincfg.Params) {
+	s.paramsLock.Lock()
+	s.params = params
+	s.paramsLock.Unlock()
+
+	s.checkpointsLock.Lock()
+	if len(s.checkpoints) == 0 || s.defaultCheckpoints {
+		s.checkpoints = DefaultCheckpoints(params)
+		s.defaultCheckpoints = true
+	}
+	s.checkpointsLock.Unlock()
+}
+
+// ChainParams returns the network of this client, the bitcoin main network by default.
+func (s *Client) ChainParams() *chaincfg.Params {
+	s.paramsLock.RLock()
+	defer s.paramsLock.RUnlock()
+
+	if s.params == nil {
+		return &chaincfg.MainNetParams
+	}
+
+	return s.params
+}
+
+// AddressToElectrumScriptHash converts an address of the client network to electrum scriptHash.
+func (s *Client) AddressToElectrumScriptHash(address string) (string, error) {
+	return AddressToElectrumScriptHash(address, s.ChainParams())
 }
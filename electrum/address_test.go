@@ -1,6 +1,7 @@
 package electrum
 
 import (
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -27,3 +28,23 @@ func TestAddressToElectrumScriptHash(t *testing.T) {
 		assert.Equal(t, scriptHash, tc.wantScriptHash)
 	}
 }
+
+func TestAddressToElectrumScriptHashNetwork(t *testing.T) {
+	_, err := AddressToElectrumScriptHash("bcrt1qcr8te4kr609gcawutmrza0j4xv80jy8zeqchgx")
+	require.Error(t, err)
+
+	_, err = AddressToElectrumScriptHash("bcrt1qcr8te4kr609gcawutmrza0j4xv80jy8zeqchgx", &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+
+	_, err = AddressToElectrumScriptHash("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", &chaincfg.TestNet3Params)
+	require.Error(t, err)
+}
+
+func TestExtendedKeyToElectrumScriptHash(t *testing.T) {
+	want, err := AddressToElectrumScriptHash("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu")
+	require.NoError(t, err)
+
+	scripthash, err := ExtendedKeyToElectrumScriptHash("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", AddressP2WPKH, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, scripthash)
+}
@@ -0,0 +1,59 @@
+package electrum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+var (
+	// ErrFeeUnavailable throws an error if the remote server cannot estimate a fee rate.
+	ErrFeeUnavailable = errors.New("fee estimate unavailable")
+)
+
+// FeeRate is a transaction fee rate in satoshis per virtual byte. It is stored in satoshis
+// per 1000 virtual bytes, so that conversions from the BTC/kB rates returned by the remote
+// server are exact.
+type FeeRate int64
+
+// FeeRateFromSatPerVByte returns the fee rate of satPerVByte satoshis per virtual byte.
+func FeeRateFromSatPerVByte(satPerVByte float64) FeeRate {
+	return FeeRate(math.Round(satPerVByte * 1000))
+}
+
+// FeeRateFromBTCPerKB returns the fee rate of btcPerKB BTC per 1000 virtual bytes, the unit
+// used by GetFee() and GetRelayFee().
+func FeeRateFromBTCPerKB(btcPerKB float64) (FeeRate, error) {
+	amount, err := btcutil.NewAmount(btcPerKB)
+	if err != nil {
+		return 0, err
+	}
+
+	return FeeRate(amount), nil
+}
+
+// SatPerVByte returns the fee rate in satoshis per virtual byte.
+func (r FeeRate) SatPerVByte() float64 {
+	return float64(r) / 1000
+}
+
+// SatPerKVByte returns the fee paid for 1000 virtual bytes.
+func (r FeeRate) SatPerKVByte() btcutil.Amount {
+	return btcutil.Amount(r)
+}
+
+// BTCPerKB returns the fee rate in BTC per 1000 virtual bytes.
+func (r FeeRate) BTCPerKB() float64 {
+	return btcutil.Amount(r).ToBTC()
+}
+
+// FeeForVSize returns the fee paid by a transaction of vsize virtual bytes, rounded up.
+func (r FeeRate) FeeForVSize(vsize int64) btcutil.Amount {
+	return btcutil.Amount((int64(r)*vsize + 999) / 1000)
+}
+
+func (r FeeRate) String() string {
+	return fmt.Sprintf("%.3f sat/vB", r.SatPerVByte())
+}
@@ -0,0 +1,38 @@
+package electrum
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeRate(t *testing.T) {
+	rate, err := FeeRateFromBTCPerKB(0.00001234)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.234, rate.SatPerVByte())
+	assert.Equal(t, btcutil.Amount(1234), rate.SatPerKVByte())
+	assert.Equal(t, 0.00001234, rate.BTCPerKB())
+	assert.Equal(t, btcutil.Amount(174), rate.FeeForVSize(141))
+	assert.Equal(t, rate, FeeRateFromSatPerVByte(1.234))
+	assert.Equal(t, "1.234 sat/vB", rate.String())
+}
+
+func TestAmounts(t *testing.T) {
+	var balance GetBalanceResp
+	require.NoError(t, json.Unmarshal([]byte(`{"result":{"confirmed":103873966,"unconfirmed":-23684}}`), &balance))
+	assert.Equal(t, btcutil.Amount(103873966), balance.Result.Confirmed)
+	assert.Equal(t, btcutil.Amount(-23684), balance.Result.Unconfirmed)
+	assert.Equal(t, btcutil.Amount(103850282), balance.Result.Total())
+
+	var vout Vout
+	require.NoError(t, json.Unmarshal([]byte(`{"n":1,"scriptpubkey":{"type":"witness_v0_keyhash"},"value":0.29000001}`), &vout))
+	assert.Equal(t, btcutil.Amount(29000001), vout.Value)
+
+	encoded, err := json.Marshal(vout)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"value":0.29000001`)
+}
@@ -0,0 +1,375 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrBatchNotSent throws an error if the result of a batch call is read before the batch is sent.
+	ErrBatchNotSent = errors.New("batch has not been sent")
+)
+
+// BatchCall is a single call queued in a Batch.
+type BatchCall struct {
+	Method string
+	Params []interface{}
+
+	id      uint64
+	content []byte
+	err     error
+}
+
+// Err returns the error returned by the remote server for this call, if any.
+func (c *BatchCall) Err() error {
+	return c.err
+}
+
+// Decode unmarshals the whole response to this call into v, for instance a GetBalanceResp.
+func (c *BatchCall) Decode(v interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return json.Unmarshal(c.content, v)
+}
+
+// Batch queues several calls to send them to the remote server as a single JSON-RPC batch.
+// A Batch must not be used concurrently and can only be sent once.
+type Batch struct {
+	client *Client
+	calls  []*BatchCall
+}
+
+// Batch returns a new empty batch of calls for this client.
+func (s *Client) Batch() *Batch {
+	return &Batch{client: s}
+}
+
+// Add queues a call to method with params.
+func (b *Batch) Add(method string, params ...interface{}) *BatchCall {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	call := &BatchCall{
+		Method: method,
+		Params: params,
+		err:    ErrBatchNotSent,
+	}
+	b.calls = append(b.calls, call)
+
+	return call
+}
+
+// Len returns the number of queued calls.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Send sends every queued call in a single message and waits for all of the responses.
+// The returned error only reports a failure of the batch itself, errors of individual
+// calls are available from each BatchCall. Like single calls, the batch is bounded by the
+// request timeout of the client, calls unavailable in the negotiated protocol version fail
+// without being sent, and every call goes through the interceptors of the client.
+func (b *Batch) Send(ctx context.Context) error {
+	s := b.client
+	if s == nil {
+		return ErrNoHealthyServer
+	}
+
+	select {
+	case <-s.quit:
+		return ErrServerShutdown
+	default:
+	}
+
+	if s.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
+		defer cancel()
+	}
+
+	calls := make([]*BatchCall, 0, len(b.calls))
+	for _, call := range b.calls {
+		if err := s.checkFeature(call.Method); err != nil {
+			call.err = err
+			continue
+		}
+		calls = append(calls, call)
+	}
+
+	if len(calls) == 0 {
+		return nil
+	}
+
+	if s.interceptor != nil {
+		return s.interceptBatch(ctx, calls)
+	}
+
+	return s.sendBatch(ctx, calls)
+}
+
+// interceptBatch runs every call through the interceptors of the client, each in its own
+// goroutine. The calls reaching the remote server are gathered in a single batch, sent once
+// every interceptor has either invoked its call or returned. A call invoked again, for
+// instance by a retry, is then sent on its own.
+func (s *Client) interceptBatch(ctx context.Context, calls []*BatchCall) error {
+	ready := make(chan *BatchCall, len(calls))
+	sent := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, call := range calls {
+		wg.Add(1)
+		go func(call *BatchCall) {
+			defer wg.Done()
+
+			var joined int32
+			invoker := func(ctx context.Context, method string, params []interface{}, v interface{}) error {
+				if !atomic.CompareAndSwapInt32(&joined, 0, 1) {
+					return s.invoke(ctx, method, params, v)
+				}
+
+				call.Method, call.Params = method, params
+				ready <- call
+				<-sent
+
+				if call.err != nil || v == nil {
+					return call.err
+				}
+
+				return json.Unmarshal(call.content, v)
+			}
+
+			var content json.RawMessage
+			err := s.interceptor(ctx, call.Method, call.Params, &content, invoker)
+			if atomic.CompareAndSwapInt32(&joined, 0, 1) {
+				ready <- nil
+			}
+
+			call.content, call.err = content, err
+		}(call)
+	}
+
+	batch := make([]*BatchCall, 0, len(calls))
+	for range calls {
+		if call := <-ready; call != nil {
+			batch = append(batch, call)
+		}
+	}
+
+	var err error
+	if len(batch) > 0 {
+		err = s.sendBatch(ctx, batch)
+	}
+	close(sent)
+	wg.Wait()
+
+	return err
+}
+
+// sendBatch sends calls in a single message and waits for all of the responses.
+func (s *Client) sendBatch(ctx context.Context, calls []*BatchCall) (err error) {
+	msgs := make([]request, len(calls))
+	pending := make(map[uint64]*BatchCall, len(calls))
+	for i, call := range calls {
+		call.id = atomic.AddUint64(&s.nextID, 1)
+		call.content, call.err = nil, ErrBatchNotSent
+		msgs[i] = request{
+			ID:     call.id,
+			Method: call.Method,
+			Params: call.Params,
+		}
+		pending[call.id] = call
+	}
+
+	bytes, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+
+	bytes = append(bytes, nl)
+
+	if s.hooks != nil {
+		ctxs := make([]context.Context, len(calls))
+		infos := make([]*RequestInfo, len(calls))
+		for i, call := range calls {
+			msg, _ := json.Marshal(msgs[i])
+			ctxs[i], infos[i] = s.requestStart(ctx, call.Method, call.id, len(msg))
+		}
+
+		defer func() {
+			for i, call := range calls {
+				callErr := call.err
+				if callErr == nil && call.content == nil {
+					callErr = err
+				}
+				s.requestEnd(ctxs[i], infos[i], &container{content: call.content}, callErr)
+			}
+		}()
+	}
+
+	transport := s.getTransport()
+	if transport == nil {
+		return ErrServerShutdown
+	}
+
+	c := make(chan *container, len(calls))
+
+	s.handlersLock.Lock()
+	for id := range pending {
+		s.handlers[id] = c
+	}
+	s.handlersLock.Unlock()
+
+	defer func() {
+		s.handlersLock.Lock()
+		for _, call := range calls {
+			delete(s.handlers, call.id)
+		}
+		s.handlersLock.Unlock()
+	}()
+
+	start := time.Now()
+
+	err = transport.SendMessage(bytes)
+	if err != nil {
+		s.log(LogError, "batch send failed", LogField{"size", len(bytes)}, LogField{"error", err})
+		if s.reconnect == nil {
+			s.Shutdown()
+		}
+		for _, call := range calls {
+			call.err = err
+		}
+		return err
+	}
+
+	logging := s.getLogger() != nil
+	if logging {
+		s.log(LogDebug, "batch sent", LogField{"count", len(calls)}, LogField{"size", len(bytes)})
+	}
+
+	for len(pending) > 0 {
+		var resp *container
+		select {
+		case resp = <-c:
+		case <-ctx.Done():
+			for _, call := range pending {
+				call.err = ErrTimeout
+			}
+			return ErrTimeout
+		}
+
+		var msg response
+		if json.Unmarshal(resp.content, &msg) != nil {
+			// The connection failed, every remaining call is failed with it.
+			if resp.content == nil {
+				for _, call := range pending {
+					call.err = resp.err
+				}
+				return resp.err
+			}
+			continue
+		}
+
+		// A response without id fails the whole batch, see dispatch().
+		if msg.ID == 0 && resp.err != nil {
+			for _, call := range pending {
+				call.err = resp.err
+			}
+			return resp.err
+		}
+
+		call, ok := pending[msg.ID]
+		if !ok {
+			continue
+		}
+
+		if logging {
+			s.log(LogDebug, "response received", LogField{"method", call.Method}, LogField{"id", msg.ID},
+				LogField{"latency", time.Since(start)}, LogField{"size", len(resp.content)},
+				s.payloadField("payload", call.Method, resp.content))
+		}
+
+		call.content = resp.content
+		call.err = withMethod(resp.err, call.Method)
+		delete(pending, msg.ID)
+	}
+
+	return nil
+}
+
+// BalanceCall is a queued GetBalance call.
+type BalanceCall struct {
+	*BatchCall
+}
+
+// GetBalance queues a call returning the confirmed and unconfirmed balance for a scripthash.
+func (b *Batch) GetBalance(scripthash string) *BalanceCall {
+	return &BalanceCall{b.Add("blockchain.scripthash.get_balance", scripthash)}
+}
+
+// Result returns the balance once the batch has been sent.
+func (c *BalanceCall) Result() (GetBalanceResult, error) {
+	var resp GetBalanceResp
+
+	err := c.Decode(&resp)
+	if err != nil {
+		return GetBalanceResult{}, err
+	}
+
+	return resp.Result, nil
+}
+
+// HistoryCall is a queued GetHistory or GetMempool call.
+type HistoryCall struct {
+	*BatchCall
+}
+
+// GetHistory queues a call returning the confirmed and unconfirmed history for a scripthash.
+func (b *Batch) GetHistory(scripthash string) *HistoryCall {
+	return &HistoryCall{b.Add("blockchain.scripthash.get_history", scripthash)}
+}
+
+// GetMempool queues a call returning the unconfirmed transacations of a scripthash.
+func (b *Batch) GetMempool(scripthash string) *HistoryCall {
+	return &HistoryCall{b.Add("blockchain.scripthash.get_mempool", scripthash)}
+}
+
+// Result returns the history once the batch has been sent.
+func (c *HistoryCall) Result() ([]*GetMempoolResult, error) {
+	var resp GetMempoolResp
+
+	err := c.Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+// UnspentCall is a queued ListUnspent call.
+type UnspentCall struct {
+	*BatchCall
+}
+
+// ListUnspent queues a call returning an ordered list of UTXOs for a scripthash.
+func (b *Batch) ListUnspent(scripthash string) *UnspentCall {
+	return &UnspentCall{b.Add("blockchain.scripthash.listunspent", scripthash)}
+}
+
+// Result returns the UTXOs once the batch has been sent.
+func (c *UnspentCall) Result() ([]*ListUnspentResult, error) {
+	var resp ListUnspentResp
+
+	err := c.Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
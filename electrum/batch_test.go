@@ -0,0 +1,211 @@
+package electrum_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reversedTransport answers the batches of its server with their responses in reverse order.
+type reversedTransport struct {
+	electrum.Transport
+	responses chan []byte
+}
+
+func reverseBatches(server *electrumtest.Server) electrum.TransportDialer {
+	return func(ctx context.Context) (electrum.Transport, error) {
+		inner, err := server.Transport()
+		if err != nil {
+			return nil, err
+		}
+
+		t := &reversedTransport{Transport: inner, responses: make(chan []byte)}
+		go func() {
+			for line := range inner.Responses() {
+				var msgs []json.RawMessage
+				if json.Unmarshal(line, &msgs) == nil {
+					for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+						msgs[i], msgs[j] = msgs[j], msgs[i]
+					}
+					line, _ = json.Marshal(msgs)
+				}
+				t.responses <- line
+			}
+		}()
+
+		return t, nil
+	}
+}
+
+func (t *reversedTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// rejectedTransport answers every batch sent through it with an error without id, as a
+// server failing to parse the whole batch does.
+type rejectedTransport struct {
+	electrum.Transport
+	server *electrumtest.Server
+}
+
+func (t *rejectedTransport) SendMessage(body []byte) error {
+	if bytes.HasPrefix(body, []byte("[")) {
+		t.server.SendRaw(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":null}`)
+		return nil
+	}
+
+	return t.Transport.SendMessage(body)
+}
+
+func TestBatchSend(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	server.SetResult("blockchain.scripthash.get_balance", map[string]int64{"confirmed": 1000, "unconfirmed": 5})
+	server.SetResult("blockchain.scripthash.get_history", []map[string]interface{}{{"tx_hash": "aa", "height": 10}})
+	server.SetError("blockchain.scripthash.listunspent", electrum.CodeBadRequest, "unknown scripthash")
+
+	client, err := electrum.NewClient(context.Background(), "", electrum.WithTransportDialer(reverseBatches(server)))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	batch := client.Batch()
+	balance := batch.GetBalance(testScripthash)
+	history := batch.GetHistory(testScripthash)
+	unspent := batch.ListUnspent(testScripthash)
+	require.NoError(t, batch.Send(context.Background()))
+
+	b, err := balance.Result()
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1000, Unconfirmed: 5}, b)
+
+	h, err := history.Result()
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: "aa", Height: 10}}, h)
+
+	_, err = unspent.Result()
+	var rpcErr *electrum.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, electrum.CodeBadRequest, rpcErr.Code)
+}
+
+func TestBatchTimeout(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client, err := server.NewClient(context.Background(), electrum.WithRequestTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	server.SetResult("blockchain.scripthash.get_balance", map[string]int64{"confirmed": 1000})
+	server.SetLatency(time.Second)
+
+	batch := client.Batch()
+	balance := batch.GetBalance(testScripthash)
+	assert.ErrorIs(t, batch.Send(context.Background()), electrum.ErrTimeout)
+
+	_, err = balance.Result()
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+}
+
+func TestBatchRejected(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	dialer := func(ctx context.Context) (electrum.Transport, error) {
+		transport, err := server.Transport()
+		if err != nil {
+			return nil, err
+		}
+
+		return &rejectedTransport{Transport: transport, server: server}, nil
+	}
+
+	client, err := electrum.NewClient(context.Background(), "", electrum.WithTransportDialer(dialer))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	// Without a request timeout, the batch fails on the error instead of waiting forever.
+	batch := client.Batch()
+	balance := batch.GetBalance(testScripthash)
+	history := batch.GetHistory(testScripthash)
+
+	var rpcErr *electrum.RPCError
+	require.ErrorAs(t, batch.Send(context.Background()), &rpcErr)
+	assert.Equal(t, electrum.CodeInvalidRequest, rpcErr.Code)
+
+	_, err = balance.Result()
+	assert.ErrorAs(t, err, &rpcErr)
+	_, err = history.Result()
+	assert.ErrorAs(t, err, &rpcErr)
+}
+
+func TestBatchVersionGating(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	server.SetResult("server.banner", "banner")
+	server.SetResult("blockchain.relayfee", 0.00001)
+	server.Handle("server.version", electrumtest.Result([]string{"test", "1.6"}))
+
+	client, err := server.NewClient(context.Background())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	batch := client.Batch()
+	relayFee := batch.Add("blockchain.relayfee")
+	banner := batch.Add("server.banner")
+	require.NoError(t, batch.Send(context.Background()))
+
+	assert.ErrorIs(t, relayFee.Err(), electrum.ErrDeprecated)
+	assert.NoError(t, banner.Err())
+	assert.Equal(t, 0, server.Calls("blockchain.relayfee"))
+}
+
+func TestBatchInterceptors(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	var lock sync.Mutex
+	var methods []string
+	record := func(ctx context.Context, method string, params []interface{}, v interface{}, invoker electrum.Invoker) error {
+		lock.Lock()
+		methods = append(methods, method)
+		lock.Unlock()
+
+		return invoker(ctx, method, params, v)
+	}
+
+	client, err := server.NewClient(context.Background(), electrum.WithInterceptors(record))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	server.SetResult("blockchain.scripthash.get_balance", map[string]int64{"confirmed": 1000})
+	server.SetResult("server.banner", "banner")
+
+	lock.Lock()
+	methods = nil
+	lock.Unlock()
+
+	batch := client.Batch()
+	balance := batch.GetBalance(testScripthash)
+	banner := batch.Add("server.banner")
+	require.NoError(t, batch.Send(context.Background()))
+
+	b, err := balance.Result()
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1000}, b)
+	assert.NoError(t, banner.Err())
+
+	assert.ElementsMatch(t, []string{"blockchain.scripthash.get_balance", "server.banner"}, methods)
+	assert.Equal(t, 1, server.Calls("blockchain.scripthash.get_balance"))
+	assert.Equal(t, 1, server.Calls("server.banner"))
+}
@@ -3,6 +3,7 @@ package electrum
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 var (
@@ -23,8 +24,17 @@ type GetBlockHeaderResult struct {
 }
 
 // GetBlockHeader returns the block header at a specific height.
+// When a checkpoint height is given, or when a trusted checkpoint set with SetCheckpoints()
+// covers height, the returned branch is verified to prove the header against the root, and
+// the root against the trusted checkpoint.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-block-header
 func (s *Client) GetBlockHeader(ctx context.Context, height uint32, checkpointHeight ...uint32) (*GetBlockHeaderResult, error) {
+	if len(checkpointHeight) == 0 {
+		if cp, ok := s.coveringCheckpoint(height); ok {
+			checkpointHeight = []uint32{cp.Height}
+		}
+	}
+
 	if checkpointHeight != nil && checkpointHeight[0] != 0 {
 		if height > checkpointHeight[0] {
 			return nil, ErrCheckpointHeight
@@ -32,6 +42,19 @@ func (s *Client) GetBlockHeader(ctx context.Context, height uint32, checkpointHe
 
 		var resp GetBlockHeaderResp
 		err := s.request(ctx, "blockchain.block.header", []interface{}{height, checkpointHeight[0]}, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		header, err := ParseBlockHeader(resp.Result.Header)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.verifyCheckpointProof(header, height, checkpointHeight[0], resp.Result.Branch, resp.Result.Root)
+		if err != nil {
+			return nil, err
+		}
 
 		return resp.Result, err
 	}
@@ -66,6 +89,9 @@ type GetBlockHeadersResult struct {
 }
 
 // GetBlockHeaders return a concatenated chunk of block headers.
+// When a checkpoint height is given, or when a trusted checkpoint set with SetCheckpoints()
+// covers the chunk, the headers are verified to link to each other and the returned branch
+// to prove the last one against the root, and the root against the trusted checkpoint.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-block-headers
 func (s *Client) GetBlockHeaders(ctx context.Context, startHeight, count uint32,
 	checkpointHeight ...uint32) (*GetBlockHeadersResult, error) {
@@ -73,12 +99,23 @@ func (s *Client) GetBlockHeaders(ctx context.Context, startHeight, count uint32,
 	var resp GetBlockHeadersResp
 	var err error
 
+	if len(checkpointHeight) == 0 && count > 0 {
+		if cp, ok := s.coveringCheckpoint(startHeight + (count - 1)); ok {
+			checkpointHeight = []uint32{cp.Height}
+		}
+	}
+
 	if checkpointHeight != nil && checkpointHeight[0] != 0 {
 		if (startHeight + (count - 1)) > checkpointHeight[0] {
 			return nil, ErrCheckpointHeight
 		}
 
 		err = s.request(ctx, "blockchain.block.headers", []interface{}{startHeight, count, checkpointHeight[0]}, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.verifyHeadersProof(resp.Result, startHeight, checkpointHeight[0])
 	} else {
 		err = s.request(ctx, "blockchain.block.headers", []interface{}{startHeight, count, 0}, &resp)
 	}
@@ -89,3 +126,25 @@ func (s *Client) GetBlockHeaders(ctx context.Context, startHeight, count uint32,
 
 	return resp.Result, err
 }
+
+// verifyHeadersProof checks a chunk of headers links together and its last header is
+// proven by the returned branch.
+func (s *Client) verifyHeadersProof(result *GetBlockHeadersResult, startHeight, checkpointHeight uint32) error {
+	headers, err := ParseBlockHeaders(result.Headers)
+	if err != nil {
+		return err
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+
+	for i := 1; i < len(headers); i++ {
+		if headers[i].PrevBlock != headers[i-1].BlockHash() {
+			return fmt.Errorf("height %d: %w", startHeight+uint32(i), ErrInvalidPrevHash)
+		}
+	}
+
+	last := startHeight + uint32(len(headers)) - 1
+
+	return s.verifyCheckpointProof(headers[len(headers)-1], last, checkpointHeight, result.Branch, result.Root)
+}
@@ -0,0 +1,151 @@
+package electrum
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrCheckpointMismatch throws an error if a header proof does not lead to the checkpoint root.
+	ErrCheckpointMismatch = errors.New("header proof does not match checkpoint")
+)
+
+// Checkpoint anchors the header chain at Height with the merkle root of the hashes of every
+// block from genesis up to Height, as returned in the root field of GetBlockHeader() and
+// GetBlockHeaders() when called with a checkpoint height.
+type Checkpoint struct {
+	Height uint32
+	Root   string
+}
+
+var (
+	// checkpoints holds the checkpoints of each network beyond its genesis block. The main
+	// network checkpoint commits to the first 256 blocks, from genesis to the block hash
+	// 00000000d0a75c861fabf9ff7b92022f60e4afeed9331fe5aa073d8e4706fe3c.
+	checkpoints = map[wire.BitcoinNet][]Checkpoint{
+		wire.MainNet: {
+			{Height: 255, Root: "0f799d144e131ca41b652c50c0818ffd1f410d8b8e32f45e9c38a1f4fbd2bad5"},
+		},
+	}
+	checkpointsLock sync.RWMutex
+)
+
+// RegisterCheckpoints adds trusted checkpoints for the network described by params.
+func RegisterCheckpoints(params *chaincfg.Params, cps ...Checkpoint) {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+
+	checkpoints[params.Net] = sortCheckpoints(append(checkpoints[params.Net], cps...))
+}
+
+// DefaultCheckpoints returns the checkpoints known for the network described by params. The
+// genesis block is always a checkpoint of its own network, and the main network ships with
+// a deeper one. Other checkpoints, for instance for the test networks, are added with
+// RegisterCheckpoints().
+func DefaultCheckpoints(params *chaincfg.Params) []Checkpoint {
+	checkpointsLock.RLock()
+	defer checkpointsLock.RUnlock()
+
+	genesis := Checkpoint{Height: 0, Root: params.GenesisHash.String()}
+
+	return sortCheckpoints(append([]Checkpoint{genesis}, checkpoints[params.Net]...))
+}
+
+func sortCheckpoints(cps []Checkpoint) []Checkpoint {
+	sorted := append([]Checkpoint(nil), cps...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Height < sorted[j].Height
+	})
+
+	return sorted
+}
+
+// VerifyHeaderProof checks branch proves the header at height is committed to by root, the
+// merkle root of the block hashes up to a checkpoint height.
+func VerifyHeaderProof(header *wire.BlockHeader, height uint32, branch []string, root string) error {
+	expected, err := chainhash.NewHashFromStr(root)
+	if err != nil {
+		return err
+	}
+
+	computed, err := merkleBranchRoot(header.BlockHash(), branch, height)
+	if err != nil {
+		return err
+	}
+
+	if computed != *expected {
+		return fmt.Errorf("%w: header at height %d leads to root %s, expected %s",
+			ErrCheckpointMismatch, height, computed, expected)
+	}
+
+	return nil
+}
+
+// SetCheckpoints replaces the trusted checkpoints header proofs are verified against. They
+// are kept when the network changes, see SetChainParams().
+func (s *Client) SetCheckpoints(cps ...Checkpoint) {
+	s.checkpointsLock.Lock()
+	s.checkpoints = sortCheckpoints(cps)
+	s.defaultCheckpoints = false
+	s.checkpointsLock.Unlock()
+}
+
+// checkpoint returns the trusted checkpoint at height, if any.
+func (s *Client) checkpoint(height uint32) (Checkpoint, bool) {
+	s.checkpointsLock.RLock()
+	defer s.checkpointsLock.RUnlock()
+
+	for _, cp := range s.checkpoints {
+		if cp.Height == height {
+			return cp, true
+		}
+	}
+
+	return Checkpoint{}, false
+}
+
+// coveringCheckpoint returns the lowest trusted checkpoint at or above height, if any.
+// The genesis checkpoint is never returned, as remote servers treat a checkpoint height
+// of 0 as no checkpoint.
+func (s *Client) coveringCheckpoint(height uint32) (Checkpoint, bool) {
+	s.checkpointsLock.RLock()
+	defer s.checkpointsLock.RUnlock()
+
+	for _, cp := range s.checkpoints {
+		if cp.Height > 0 && cp.Height >= height {
+			return cp, true
+		}
+	}
+
+	return Checkpoint{}, false
+}
+
+// verifyCheckpointProof checks the header proof returned for a checkpoint height, and that
+// its root matches the trusted checkpoint at that height when there is one.
+func (s *Client) verifyCheckpointProof(header *wire.BlockHeader, height, checkpointHeight uint32,
+	branch []string, root string) error {
+
+	if cp, ok := s.checkpoint(checkpointHeight); ok {
+		trusted, err := chainhash.NewHashFromStr(cp.Root)
+		if err != nil {
+			return err
+		}
+		returned, err := chainhash.NewHashFromStr(root)
+		if err != nil {
+			return err
+		}
+
+		if *trusted != *returned {
+			return fmt.Errorf("%w: remote server root %s at height %d, expected %s",
+				ErrCheckpointMismatch, returned, checkpointHeight, trusted)
+		}
+	}
+
+	return VerifyHeaderProof(header, height, branch, root)
+}
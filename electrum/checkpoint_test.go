@@ -0,0 +1,220 @@
+package electrum_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainCheckpoint returns the checkpoint of chain at height, computed from its headers.
+func chainCheckpoint(t *testing.T, chain *electrumtest.Chain, height int32) electrum.Checkpoint {
+	t.Helper()
+
+	leaves := make([]chainhash.Hash, height+1)
+	for i := range leaves {
+		header, err := chain.Header(int32(i))
+		require.NoError(t, err)
+		leaves[i] = header.BlockHash()
+	}
+	_, root := electrum.MerkleBranch(leaves, 0)
+
+	return electrum.Checkpoint{Height: uint32(height), Root: root.String()}
+}
+
+func TestDefaultCheckpoints(t *testing.T) {
+	for _, params := range []*chaincfg.Params{&chaincfg.MainNetParams, &chaincfg.TestNet3Params, &chaincfg.SigNetParams} {
+		cps := electrum.DefaultCheckpoints(params)
+		require.NotEmpty(t, cps, params.Name)
+		assert.Equal(t, electrum.Checkpoint{Height: 0, Root: params.GenesisHash.String()}, cps[0], params.Name)
+	}
+
+	mainnet := electrum.DefaultCheckpoints(&chaincfg.MainNetParams)
+	require.Len(t, mainnet, 2)
+	assert.Equal(t, uint32(255), mainnet[1].Height)
+}
+
+func TestMainNetCheckpoint(t *testing.T) {
+	file, err := os.Open("testdata/mainnet-headers-0-255.hex")
+	require.NoError(t, err)
+	defer file.Close()
+
+	var leaves []chainhash.Hash
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		raw, err := hex.DecodeString(scanner.Text())
+		require.NoError(t, err)
+
+		var header wire.BlockHeader
+		require.NoError(t, header.Deserialize(bytes.NewReader(raw)))
+		if len(leaves) > 0 {
+			require.Equal(t, leaves[len(leaves)-1], header.PrevBlock)
+		}
+		leaves = append(leaves, header.BlockHash())
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, leaves, 256)
+	assert.Equal(t, *chaincfg.MainNetParams.GenesisHash, leaves[0])
+
+	_, root := electrum.MerkleBranch(leaves, 0)
+	assert.Equal(t, "0f799d144e131ca41b652c50c0818ffd1f410d8b8e32f45e9c38a1f4fbd2bad5", root.String())
+	assert.Equal(t, electrum.Checkpoint{Height: 255, Root: root.String()}, electrum.DefaultCheckpoints(&chaincfg.MainNetParams)[1])
+}
+
+func TestChainParamsCheckpoints(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+	chain.Mine(10)
+
+	newClient := func() *electrum.Client {
+		client, err := server.NewClient(context.Background(), electrum.WithChainParams(&chaincfg.MainNetParams))
+		require.NoError(t, err)
+		t.Cleanup(client.Shutdown)
+		return client
+	}
+
+	t.Run("client", func(t *testing.T) {
+		client := newClient()
+
+		// The main network checkpoint at height 255 cannot be proven by the remote chain.
+		_, err := client.GetBlockHeader(context.Background(), 5)
+		assert.Error(t, err)
+
+		// The defaults of the new network replace the ones of the previous network.
+		client.SetChainParams(chain.Params())
+		result, err := client.GetBlockHeader(context.Background(), 5)
+		require.NoError(t, err)
+		assert.Empty(t, result.Root)
+
+		// Checkpoints set explicitly are kept across network changes.
+		cp := chainCheckpoint(t, chain, 8)
+		client.SetCheckpoints(cp)
+		client.SetChainParams(&chaincfg.MainNetParams)
+		result, err = client.GetBlockHeader(context.Background(), 5)
+		require.NoError(t, err)
+		assert.Equal(t, cp.Root, result.Root)
+	})
+
+	t.Run("pool", func(t *testing.T) {
+		pool := electrum.NewPool(&electrum.PoolConfig{}, newClient())
+		defer pool.Shutdown()
+
+		pool.SetChainParams(chain.Params())
+		pool.Add(newClient())
+
+		for i := 0; i < 2; i++ {
+			result, err := pool.GetBlockHeader(context.Background(), 5)
+			require.NoError(t, err)
+			assert.Empty(t, result.Root)
+		}
+	})
+}
+
+func TestCheckpointVerification(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+	chain.Mine(10)
+
+	client, err := server.NewClient(context.Background())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	cp := chainCheckpoint(t, chain, 8)
+
+	t.Run("valid", func(t *testing.T) {
+		client.SetCheckpoints(cp)
+
+		result, err := client.GetBlockHeader(context.Background(), 5)
+		require.NoError(t, err)
+		assert.Equal(t, cp.Root, result.Root)
+
+		headers, err := client.GetBlockHeaders(context.Background(), 2, 4)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(4), headers.Count)
+		assert.Equal(t, cp.Root, headers.Root)
+
+		// Heights above the trusted checkpoints are not verified.
+		result, err = client.GetBlockHeader(context.Background(), 9)
+		require.NoError(t, err)
+		assert.Empty(t, result.Root)
+	})
+
+	t.Run("wrong root", func(t *testing.T) {
+		// The remote server answers with the root of its chain up to height 7, checked
+		// against a trusted checkpoint claiming another root at that height.
+		client.SetCheckpoints(electrum.Checkpoint{Height: 7, Root: cp.Root})
+		defer client.SetCheckpoints()
+
+		_, err := client.GetBlockHeader(context.Background(), 5)
+		assert.ErrorIs(t, err, electrum.ErrCheckpointMismatch)
+
+		_, err = client.GetBlockHeaders(context.Background(), 2, 4)
+		assert.ErrorIs(t, err, electrum.ErrCheckpointMismatch)
+	})
+
+	t.Run("tampered branch", func(t *testing.T) {
+		client.SetCheckpoints(cp)
+		defer client.SetCheckpoints()
+
+		result, err := client.GetBlockHeader(context.Background(), 5)
+		require.NoError(t, err)
+
+		branch := append([]string(nil), result.Branch...)
+		branch[0] = chainhash.Hash{}.String()
+		server.SetResult("blockchain.block.header", &electrum.GetBlockHeaderResult{
+			Branch: branch,
+			Header: result.Header,
+			Root:   result.Root,
+		})
+
+		_, err = client.GetBlockHeader(context.Background(), 5)
+		assert.ErrorIs(t, err, electrum.ErrCheckpointMismatch)
+	})
+}
+
+func TestHeaderSyncCheckpoint(t *testing.T) {
+	chain, sync, store := newHeaderSync(t)
+	chain.Mine(10)
+
+	t.Run("wrong root", func(t *testing.T) {
+		sync.Checkpoint = &electrum.Checkpoint{Height: 6, Root: chainCheckpoint(t, chain, 5).Root}
+
+		_, err := sync.Sync(context.Background())
+		assert.ErrorIs(t, err, electrum.ErrCheckpointMismatch)
+
+		tip, err := store.Tip()
+		require.NoError(t, err)
+		assert.Equal(t, int32(-1), tip)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		cp := chainCheckpoint(t, chain, 6)
+		sync.Checkpoint = &cp
+
+		notif, err := sync.Sync(context.Background())
+		require.NoError(t, err)
+		assertTip(t, chain, notif)
+
+		// The store starts at the checkpoint instead of genesis.
+		_, err = store.Header(5)
+		assert.ErrorIs(t, err, electrum.ErrHeaderNotFound)
+
+		expected, err := chain.Header(6)
+		require.NoError(t, err)
+		header, err := store.Header(6)
+		require.NoError(t, err)
+		assert.Equal(t, expected.BlockHash(), header.BlockHash())
+	})
+}
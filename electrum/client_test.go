@@ -0,0 +1,466 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScripthash = "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"
+
+func TestClientCannedResponses(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	server.SetResult("blockchain.scripthash.get_balance", map[string]int64{"confirmed": 150000, "unconfirmed": -2000})
+	server.SetError("blockchain.transaction.get", electrum.CodeDaemonError,
+		"daemon error: DaemonError({'code': -5, 'message': 'No such mempool or blockchain transaction.'})")
+
+	client, err := server.NewClient(context.Background())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	assert.Equal(t, "1.4", client.NegotiatedVersion())
+
+	balance, err := client.GetBalance(context.Background(), testScripthash)
+	require.NoError(t, err)
+	assert.Equal(t, btcutil.Amount(150000), balance.Confirmed)
+	assert.Equal(t, btcutil.Amount(-2000), balance.Unconfirmed)
+
+	_, err = client.GetRawTransaction(context.Background(), "f3e1bf48975b8d6060a9de8884296abb80be618dc00ae3cb2f6cee3085e09403")
+	assert.ErrorIs(t, err, electrum.ErrNotFound)
+
+	_, err = client.ServerBanner(context.Background())
+	assert.ErrorIs(t, err, electrum.ErrMethodNotFound)
+
+	reqs := server.Requests()
+	require.Len(t, reqs, 4)
+	assert.Equal(t, "blockchain.scripthash.get_balance", reqs[1].Method)
+	assert.Equal(t, json.RawMessage(`"`+testScripthash+`"`), reqs[1].Params[0])
+}
+
+func TestClientNotifications(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	server.SetResult("blockchain.headers.subscribe", map[string]interface{}{"height": 100, "hex": "00"})
+
+	addr, err := server.Listen()
+	require.NoError(t, err)
+
+	client, err := electrum.NewClient(context.Background(), "tcp://"+addr)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(100), (<-headers).Height)
+
+	// Malformed lines are skipped.
+	server.SendRaw("{not json")
+	require.NoError(t, server.Notify("blockchain.headers.subscribe", map[string]interface{}{"height": 101, "hex": "01"}))
+
+	select {
+	case header := <-headers:
+		assert.Equal(t, int32(101), header.Height)
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+	}
+}
+
+func TestClientLatency(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client, err := server.NewClient(context.Background())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	server.SetLatency(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = client.Ping(ctx)
+	assert.ErrorIs(t, err, electrum.ErrTimeout)
+}
+
+func TestClientReconnect(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	client, err := server.NewClient(context.Background(), electrum.WithReconnect(&electrum.ReconnectConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		Multiplier:     2,
+		DialTimeout:    time.Second,
+	}))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	server.Disconnect()
+
+	assert.Eventually(t, func() bool {
+		return server.Calls("server.version") == 2
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, client.Ping(context.Background()))
+}
+
+func TestClientScripthashSubscriptionBacklog(t *testing.T) {
+	ctx := context.Background()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	server.SetResult("blockchain.scripthash.subscribe", "initial")
+
+	client, err := server.NewClient(ctx)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	// The initial statuses of every scripthash are delivered while nobody reads the
+	// notifications, whatever the buffer size.
+	scripthashes := make([]string, 5)
+	sub, notifs := client.SubscribeScripthash()
+	for i := range scripthashes {
+		scripthashes[i] = string(rune('a'+i)) + testScripthash[1:]
+		require.NoError(t, sub.Add(ctx, scripthashes[i]))
+	}
+
+	// A status not yet delivered is replaced by the newer one of the same scripthash.
+	require.NoError(t, server.Notify("blockchain.scripthash.subscribe", scripthashes[4], "updated"))
+
+	received := make(map[string]string)
+	for len(received) < len(scripthashes) {
+		select {
+		case notif := <-notifs:
+			received[notif.Params[0]] = notif.Params[1]
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d statuses", len(received), len(scripthashes))
+		}
+	}
+
+	for _, scripthash := range scripthashes[:4] {
+		assert.Equal(t, "initial", received[scripthash])
+	}
+	assert.Eventually(t, func() bool {
+		if received[scripthashes[4]] == "updated" {
+			return true
+		}
+		select {
+		case notif := <-notifs:
+			received[notif.Params[0]] = notif.Params[1]
+		default:
+		}
+		return false
+	}, time.Second, time.Millisecond)
+}
+
+func TestClientReconnectSubscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	server.SetResult("blockchain.headers.subscribe", &electrum.SubscribeHeadersResult{Height: 1, Hex: "00"})
+	server.SetResult("blockchain.scripthash.subscribe", nil)
+
+	client, err := server.NewClient(ctx, electrum.WithReconnect(&electrum.ReconnectConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		Multiplier:     2,
+		DialTimeout:    time.Second,
+	}), electrum.WithBufferSizes(0, 8))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	<-headers
+
+	removed := "00" + testScripthash[2:]
+	sub, notifs := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, testScripthash))
+	require.NoError(t, sub.Add(ctx, removed))
+	require.NoError(t, sub.Remove(removed))
+
+	server.Disconnect()
+
+	// The headers and the remaining scripthash are subscribed again after the reconnection.
+	assert.Eventually(t, func() bool {
+		return server.Calls("blockchain.headers.subscribe") == 2 &&
+			server.Calls("blockchain.scripthash.subscribe") == 3
+	}, time.Second, time.Millisecond)
+	<-headers
+
+	require.NoError(t, server.Notify("blockchain.headers.subscribe", &electrum.SubscribeHeadersResult{Height: 2, Hex: "00"}))
+	select {
+	case header := <-headers:
+		assert.Equal(t, int32(2), header.Height)
+	case <-time.After(time.Second):
+		t.Fatal("header not received after reconnection")
+	}
+
+	require.NoError(t, server.Notify("blockchain.scripthash.subscribe", testScripthash, "status"))
+	select {
+	case notif := <-notifs:
+		assert.Equal(t, [2]string{testScripthash, "status"}, notif.Params)
+	case <-time.After(time.Second):
+		t.Fatal("scripthash notification not received after reconnection")
+	}
+
+	var resubscribed []string
+	for _, req := range server.Requests() {
+		if req.Method == "blockchain.scripthash.subscribe" {
+			resubscribed = append(resubscribed, string(req.Params[0]))
+		}
+	}
+	assert.Equal(t, `"`+testScripthash+`"`, resubscribed[2], "removed scripthash is not replayed")
+}
+
+func TestClientVerifiedSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(ctx)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	script := []byte{txscript.OP_TRUE, txscript.OP_TRUE}
+	scripthash := electrum.ScriptToElectrumScriptHash(script)
+
+	sub, diffs := client.SubscribeScripthashVerified(ctx)
+	require.NoError(t, sub.Add(ctx, scripthash))
+
+	receive := func() *electrum.HistoryDiff {
+		select {
+		case diff := <-diffs:
+			return diff
+		case err := <-sub.Errors():
+			t.Fatal(err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("diff not received")
+		}
+		return nil
+	}
+
+	tx, err := chain.Fund(script, 1000)
+	require.NoError(t, err)
+	txid := tx.TxHash().String()
+
+	diff := receive()
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: txid, Height: 0}}, diff.Added)
+
+	chain.Mine(1)
+	diff = receive()
+	assert.Empty(t, diff.Added)
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: txid, Height: 1}}, diff.Confirmed)
+	assert.Equal(t, diff.History, sub.History(scripthash))
+
+	// A status not matching the history is reported.
+	require.NoError(t, server.Notify("blockchain.scripthash.subscribe", scripthash, testScripthash))
+	select {
+	case err := <-sub.Errors():
+		assert.ErrorIs(t, err, electrum.ErrStatusMismatch)
+	case <-time.After(2 * time.Second):
+		t.Fatal("mismatch not reported")
+	}
+}
+
+func TestClientVerifiedSubscriptionInitialDiffs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(ctx)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	scripthashes := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		script := []byte{txscript.OP_DATA_1, byte(i), txscript.OP_DROP, txscript.OP_TRUE}
+		_, err := chain.Fund(script, 1000)
+		require.NoError(t, err)
+		scripthashes[electrum.ScriptToElectrumScriptHash(script)] = true
+	}
+	chain.Mine(1)
+
+	sub, diffs := client.SubscribeScripthashVerified(ctx)
+	for scripthash := range scripthashes {
+		require.NoError(t, sub.Add(ctx, scripthash))
+	}
+
+	// Every funded scripthash is delivered its whole history once.
+	for len(scripthashes) > 0 {
+		select {
+		case diff := <-diffs:
+			assert.True(t, scripthashes[diff.Scripthash], diff.Scripthash)
+			delete(scripthashes, diff.Scripthash)
+			require.Len(t, diff.Added, 1)
+			assert.Equal(t, int32(1), diff.Added[0].Height)
+		case err := <-sub.Errors():
+			t.Fatal(err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%d diffs not received", len(scripthashes))
+		}
+	}
+
+	select {
+	case diff := <-diffs:
+		t.Fatalf("unexpected diff for %s", diff.Scripthash)
+	case err := <-sub.Errors():
+		t.Fatal(err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClientScripthashEventsConcurrentAdd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(ctx)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	scripts := make([][]byte, 10)
+	for i := range scripts {
+		scripts[i] = []byte{txscript.OP_DATA_1, byte(i), txscript.OP_DROP, txscript.OP_TRUE}
+	}
+
+	sub, events := client.SubscribeScripthashEvents(ctx)
+	require.NoError(t, sub.Add(ctx, electrum.ScriptToElectrumScriptHash(scripts[0]), "address0"))
+
+	// The events of the first scripthash look up its address while others are added.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_, err := chain.Fund(scripts[0], 1000)
+			assert.NoError(t, err)
+		}
+	}()
+
+	for i := 1; i < len(scripts); i++ {
+		scripthash := electrum.ScriptToElectrumScriptHash(scripts[i])
+		require.NoError(t, sub.Add(ctx, scripthash, fmt.Sprintf("address%d", i)))
+	}
+	wg.Wait()
+
+	// Funded faster than notified, the scripthash may report outdated statuses on Errors(),
+	// only its events are checked.
+	for {
+		select {
+		case event := <-events:
+			assert.Equal(t, "address0", event.Address)
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestClientScripthashEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(ctx)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	script := []byte{txscript.OP_TRUE, txscript.OP_TRUE}
+	scripthash := electrum.ScriptToElectrumScriptHash(script)
+
+	sub, events := client.SubscribeScripthashEvents(ctx)
+	require.NoError(t, sub.Add(ctx, scripthash, "watched"))
+
+	receive := func(n int) []*electrum.ScripthashEvent {
+		var received []*electrum.ScripthashEvent
+		for len(received) < n {
+			select {
+			case event := <-events:
+				assert.Equal(t, "watched", event.Address)
+				received = append(received, event)
+			case err := <-sub.Errors():
+				t.Fatal(err)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%d events received, expected %d", len(received), n)
+			}
+		}
+		return received
+	}
+
+	funding, err := chain.Fund(script, 5000)
+	require.NoError(t, err)
+	fundingHash := funding.TxHash()
+
+	received := receive(2)
+	assert.Equal(t, electrum.EventMempoolTx, received[0].Type)
+	assert.Equal(t, fundingHash.String(), received[0].TxHash)
+	assert.Equal(t, electrum.EventBalanceChanged, received[1].Type)
+	assert.Equal(t, btcutil.Amount(5000), received[1].Balance.Unconfirmed)
+
+	chain.Mine(1)
+	received = receive(2)
+	assert.Equal(t, electrum.EventTxConfirmed, received[0].Type)
+	assert.Equal(t, int32(1), received[0].Height)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 5000}, received[1].Balance)
+
+	spend := func(fee int64) *wire.MsgTx {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundingHash, 0), nil, nil))
+		tx.AddTxOut(wire.NewTxOut(5000-fee, []byte{txscript.OP_TRUE}))
+		require.NoError(t, chain.AddTransaction(tx))
+		return tx
+	}
+
+	first := spend(100)
+	received = receive(2)
+	assert.Equal(t, electrum.EventMempoolTx, received[0].Type)
+	assert.Equal(t, electrum.EventBalanceChanged, received[1].Type)
+
+	// The replacement spends the same output, the balance does not change.
+	replacement := spend(200)
+	received = receive(2)
+	assert.Equal(t, electrum.EventTxDropped, received[0].Type)
+	assert.Equal(t, first.TxHash().String(), received[0].TxHash)
+	assert.Equal(t, electrum.EventMempoolTx, received[1].Type)
+	assert.Equal(t, replacement.TxHash().String(), received[1].TxHash)
+
+	chain.Mine(1)
+	received = receive(2)
+	assert.Equal(t, electrum.EventTxConfirmed, received[0].Type)
+	assert.Equal(t, int32(2), received[0].Height)
+	assert.Equal(t, electrum.GetBalanceResult{}, received[1].Balance)
+
+	require.NoError(t, chain.Reorg(1))
+	received = receive(2)
+	assert.Equal(t, electrum.EventTxReorged, received[0].Type)
+	assert.Equal(t, replacement.TxHash().String(), received[0].TxHash)
+	assert.Equal(t, int32(0), received[0].Height)
+	assert.Equal(t, electrum.EventBalanceChanged, received[1].Type)
+}
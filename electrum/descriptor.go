@@ -0,0 +1,274 @@
+package electrum
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+var (
+	// ErrInvalidDescriptor throws an error if an output descriptor cannot be parsed.
+	ErrInvalidDescriptor = errors.New("invalid output descriptor")
+
+	// ErrDescriptorChecksum throws an error if an output descriptor checksum does not match.
+	ErrDescriptorChecksum = errors.New("invalid output descriptor checksum")
+)
+
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// descriptorChecksum computes the BIP380 checksum of an output descriptor.
+func descriptorChecksum(desc string) (string, error) {
+	generator := [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+	chk := uint64(1)
+	polymod := func(value uint64) {
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ value
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	var groups []uint64
+	for _, c := range desc {
+		v := strings.IndexRune(descriptorInputCharset, c)
+		if v < 0 {
+			return "", ErrInvalidDescriptor
+		}
+
+		polymod(uint64(v & 31))
+		groups = append(groups, uint64(v>>5))
+		if len(groups) == 3 {
+			polymod(groups[0]*9 + groups[1]*3 + groups[2])
+			groups = groups[:0]
+		}
+	}
+	if len(groups) == 1 {
+		polymod(groups[0])
+	} else if len(groups) == 2 {
+		polymod(groups[0]*3 + groups[1])
+	}
+	for i := 0; i < 8; i++ {
+		polymod(0)
+	}
+	chk ^= 1
+
+	checksum := make([]byte, 8)
+	for i := range checksum {
+		checksum[i] = descriptorChecksumCharset[(chk>>(5*(7-uint(i))))&31]
+	}
+
+	return string(checksum), nil
+}
+
+// DescriptorToScript returns the output script described by desc, deriving ranged extended
+// keys at index. Supported descriptors are addr(), raw(), pk(), pkh(), wpkh(), sh(wpkh())
+// and key path only tr(), with hex public keys, x-only in tr(), or extended public keys
+// followed by an optional unhardened derivation path. When present, the checksum is verified.
+func DescriptorToScript(desc string, index uint32, params *chaincfg.Params) ([]byte, error) {
+	if i := strings.IndexByte(desc, '#'); i >= 0 {
+		checksum, err := descriptorChecksum(desc[:i])
+		if err != nil {
+			return nil, err
+		}
+		if checksum != desc[i+1:] {
+			return nil, ErrDescriptorChecksum
+		}
+
+		desc = desc[:i]
+	}
+
+	fn, arg, err := splitDescriptor(desc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fn {
+	case "addr":
+		address, err := btcutil.DecodeAddress(arg, params)
+		if err != nil {
+			return nil, err
+		}
+		if !address.IsForNet(params) {
+			return nil, ErrWrongNetwork
+		}
+
+		return txscript.PayToAddrScript(address)
+
+	case "raw":
+		return hex.DecodeString(arg)
+
+	case "pk":
+		_, raw, err := parseDescriptorKey(arg, index, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return txscript.NewScriptBuilder().AddData(raw).AddOp(txscript.OP_CHECKSIG).Script()
+
+	case "pkh":
+		_, raw, err := parseDescriptorKey(arg, index, false)
+		if err != nil {
+			return nil, err
+		}
+
+		address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(raw), params)
+		if err != nil {
+			return nil, err
+		}
+
+		return txscript.PayToAddrScript(address)
+
+	case "wpkh", "tr":
+		pubKey, raw, err := parseDescriptorKey(arg, index, fn == "tr")
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) > btcec.PubKeyBytesLenCompressed {
+			return nil, fmt.Errorf("%w: uncompressed key in %s()", ErrInvalidDescriptor, fn)
+		}
+
+		addrType := map[string]AddressType{"wpkh": AddressP2WPKH, "tr": AddressP2TR}[fn]
+		address, err := PubKeyToAddress(pubKey, addrType, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return txscript.PayToAddrScript(address)
+
+	case "sh":
+		inner, key, err := splitDescriptor(arg)
+		if err != nil {
+			return nil, err
+		}
+		if inner != "wpkh" {
+			return nil, fmt.Errorf("%w: unsupported sh(%s())", ErrInvalidDescriptor, inner)
+		}
+
+		pubKey, raw, err := parseDescriptorKey(key, index, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) > btcec.PubKeyBytesLenCompressed {
+			return nil, fmt.Errorf("%w: uncompressed key in sh(wpkh())", ErrInvalidDescriptor)
+		}
+
+		address, err := PubKeyToAddress(pubKey, AddressP2SHP2WPKH, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return txscript.PayToAddrScript(address)
+	}
+
+	return nil, fmt.Errorf("%w: unsupported %s()", ErrInvalidDescriptor, fn)
+}
+
+// DescriptorToElectrumScriptHash converts the output described by desc, derived at index
+// for ranged descriptors, to electrum scriptHash.
+func DescriptorToElectrumScriptHash(desc string, index uint32, params *chaincfg.Params) (string, error) {
+	script, err := DescriptorToScript(desc, index, params)
+	if err != nil {
+		return "", err
+	}
+
+	return ScriptToElectrumScriptHash(script), nil
+}
+
+// splitDescriptor splits fn(arg) into fn and arg.
+func splitDescriptor(desc string) (string, string, error) {
+	open := strings.IndexByte(desc, '(')
+	if open <= 0 || !strings.HasSuffix(desc, ")") {
+		return "", "", ErrInvalidDescriptor
+	}
+
+	return desc[:open], desc[open+1 : len(desc)-1], nil
+}
+
+// parseDescriptorKey parses a descriptor key expression, with an optional origin, and
+// derives it at index when it is ranged. Along with the key, it returns its encoding in
+// the expression, or its compressed encoding for extended keys. 32 bytes x-only keys are
+// only accepted when xOnly is set.
+func parseDescriptorKey(expr string, index uint32, xOnly bool) (*btcec.PublicKey, []byte, error) {
+	if strings.HasPrefix(expr, "[") {
+		end := strings.IndexByte(expr, ']')
+		if end < 0 {
+			return nil, nil, ErrInvalidDescriptor
+		}
+
+		expr = expr[end+1:]
+	}
+
+	parts := strings.Split(expr, "/")
+	key, err := hdkeychain.NewKeyFromString(parts[0])
+	if err != nil && len(parts) == 1 {
+		raw, err := hex.DecodeString(expr)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var pubKey *btcec.PublicKey
+		if xOnly && len(raw) == schnorr.PubKeyBytesLen {
+			pubKey, err = schnorr.ParsePubKey(raw)
+		} else {
+			pubKey, err = btcec.ParsePubKey(raw)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return pubKey, raw, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if key.IsPrivate() {
+		return nil, nil, fmt.Errorf("%w: private keys are not supported", ErrInvalidDescriptor)
+	}
+
+	for _, step := range parts[1:] {
+		var child uint32
+		switch step {
+		case "*":
+			child = index
+		case "":
+			return nil, nil, ErrInvalidDescriptor
+		default:
+			if strings.ContainsAny(step, "'h") {
+				return nil, nil, fmt.Errorf("%w: hardened derivation from a public key", ErrInvalidDescriptor)
+			}
+
+			n, err := strconv.ParseUint(step, 10, 31)
+			if err != nil {
+				return nil, nil, ErrInvalidDescriptor
+			}
+			child = uint32(n)
+		}
+
+		key, err = key.Derive(child)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pubKey, pubKey.SerializeCompressed(), nil
+}
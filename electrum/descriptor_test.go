@@ -0,0 +1,116 @@
+package electrum
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptorChecksum(t *testing.T) {
+	checksum, err := descriptorChecksum("raw(deadbeef)")
+	require.NoError(t, err)
+	assert.Equal(t, "89f8spxm", checksum)
+
+	_, err = DescriptorToScript("raw(deadbeef)#89f8spxx", 0, &chaincfg.MainNetParams)
+	assert.ErrorIs(t, err, ErrDescriptorChecksum)
+}
+
+func TestDescriptorToElectrumScriptHash(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		index      uint32
+		address    string
+	}{
+		{
+			descriptor: "addr(1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa)",
+			address:    "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		},
+		{
+			descriptor: "wpkh([73c5da0a/84'/0'/0']zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/0/*)",
+			address:    "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+		},
+		{
+			descriptor: "sh(wpkh(ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP/0/*))",
+			address:    "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf",
+		},
+		{
+			descriptor: "tr(xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)",
+			address:    "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+		},
+	}
+
+	for _, tc := range tests {
+		want, err := AddressToElectrumScriptHash(tc.address)
+		require.NoError(t, err)
+
+		scripthash, err := DescriptorToElectrumScriptHash(tc.descriptor, tc.index, &chaincfg.MainNetParams)
+		require.NoError(t, err, tc.descriptor)
+		assert.Equal(t, want, scripthash, tc.descriptor)
+	}
+}
+
+func TestDescriptorToScript(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		script     string
+	}{
+		{
+			// Uncompressed keys keep their encoding.
+			descriptor: "pk(04a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235)",
+			script:     "4104a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235ac",
+		},
+		{
+			descriptor: "pkh(04a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235)",
+			script:     "76a914b5bd079c4d57cc7fc28ecf8213a6b791625b818388ac",
+		},
+		{
+			descriptor: "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)",
+			script:     "512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11",
+		},
+	}
+
+	for _, tc := range tests {
+		script, err := DescriptorToScript(tc.descriptor, 0, &chaincfg.MainNetParams)
+		require.NoError(t, err, tc.descriptor)
+		assert.Equal(t, tc.script, hex.EncodeToString(script), tc.descriptor)
+	}
+
+	// An x-only key is the even key of its x coordinate.
+	xOnly, err := DescriptorToScript("tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)", 0, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	even, err := DescriptorToScript("tr(02a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)", 0, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, even, xOnly)
+
+	// x-only keys are only valid in tr().
+	_, err = DescriptorToScript("wpkh(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)", 0, &chaincfg.MainNetParams)
+	assert.Error(t, err)
+
+	// Uncompressed keys are not valid in witness outputs.
+	const uncompressed = "04a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235"
+	for _, desc := range []string{"wpkh(" + uncompressed + ")", "sh(wpkh(" + uncompressed + "))", "tr(" + uncompressed + ")"} {
+		_, err = DescriptorToScript(desc, 0, &chaincfg.MainNetParams)
+		assert.ErrorIs(t, err, ErrInvalidDescriptor, desc)
+	}
+}
+
+func TestDescriptorBareExtendedKey(t *testing.T) {
+	const xpub = "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ"
+
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	require.NoError(t, err)
+	pubKey, err := key.ECPubKey()
+	require.NoError(t, err)
+	address, err := PubKeyToAddress(pubKey, AddressP2WPKH, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	want, err := AddressToElectrumScriptHash(address.EncodeAddress())
+	require.NoError(t, err)
+
+	scripthash, err := DescriptorToElectrumScriptHash("wpkh([73c5da0a/84'/0'/0']"+xpub+")", 0, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, want, scripthash)
+}
@@ -0,0 +1,84 @@
+package electrum
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+
+	"golang.org/x/net/proxy"
+)
+
+var (
+	// ErrDialerUnsupported throws an error if a proxy dialer cannot dial with a context.
+	ErrDialerUnsupported = errors.New("proxy dialer does not support contexts")
+)
+
+// Dialer opens network connections for the transports. *net.Dialer and the dialers returned
+// by NewSOCKS5Dialer() and NewTorDialer() implement it.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func defaultDialer(dialer []Dialer) Dialer {
+	if len(dialer) > 0 && dialer[0] != nil {
+		return dialer[0]
+	}
+
+	return &net.Dialer{}
+}
+
+// SOCKS5Auth holds the credentials sent to a SOCKS5 proxy.
+type SOCKS5Auth struct {
+	User     string
+	Password string
+}
+
+type forwardDialer struct {
+	Dialer
+}
+
+func (d forwardDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// NewSOCKS5Dialer returns a Dialer connecting through the SOCKS5 proxy at proxyAddr. Host
+// names are resolved by the proxy, so .onion addresses can be dialed through Tor. The proxy
+// itself is reached with forward, or directly when omitted.
+func NewSOCKS5Dialer(proxyAddr string, auth *SOCKS5Auth, forward ...Dialer) (Dialer, error) {
+	var proxyAuth *proxy.Auth
+	if auth != nil {
+		proxyAuth = &proxy.Auth{User: auth.User, Password: auth.Password}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", proxyAddr, proxyAuth, forwardDialer{defaultDialer(forward)})
+	if err != nil {
+		return nil, err
+	}
+
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, ErrDialerUnsupported
+	}
+
+	return contextDialer, nil
+}
+
+// NewTorDialer returns a Dialer connecting through the Tor SOCKS5 proxy at proxyAddr, usually
+// 127.0.0.1:9050. Every dialer sends its own random credentials, so that Tor isolates its
+// connections on a separate circuit from the other dialers, for instance one per wallet.
+func NewTorDialer(proxyAddr string) (Dialer, error) {
+	isolation := make([]byte, 16)
+	_, err := rand.Read(isolation)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &SOCKS5Auth{
+		User:     hex.EncodeToString(isolation[:8]),
+		Password: hex.EncodeToString(isolation[8:]),
+	}
+
+	return NewSOCKS5Dialer(proxyAddr, auth)
+}
@@ -0,0 +1,229 @@
+package electrum_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socks5Request is a CONNECT request received by a socks5Proxy.
+type socks5Request struct {
+	User     string
+	Password string
+	Addr     string
+}
+
+// socks5Proxy is a minimal SOCKS5 proxy connecting every request to target, whatever the
+// requested address, and recording the requests.
+type socks5Proxy struct {
+	listener net.Listener
+	target   string
+
+	requests []socks5Request
+	lock     sync.Mutex
+}
+
+func newSOCKS5Proxy(t *testing.T, target string) *socks5Proxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	p := &socks5Proxy{listener: listener, target: target}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+
+	return p
+}
+
+func (p *socks5Proxy) Requests() []socks5Request {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]socks5Request(nil), p.requests...)
+}
+
+func (p *socks5Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	req, err := p.handshake(conn)
+	if err != nil {
+		return
+	}
+
+	target, err := net.Dial("tcp", p.target)
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+
+	p.lock.Lock()
+	p.requests = append(p.requests, req)
+	p.lock.Unlock()
+
+	_, err = conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	if err != nil {
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(target, conn)
+		target.Close()
+	}()
+	_, _ = io.Copy(conn, target)
+}
+
+// handshake negotiates the authentication, preferring username and password, and reads
+// the CONNECT request.
+func (p *socks5Proxy) handshake(conn net.Conn) (socks5Request, error) {
+	var req socks5Request
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return req, err
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return req, err
+	}
+
+	method := byte(0)
+	for _, m := range methods {
+		if m == 2 {
+			method = 2
+		}
+	}
+	if _, err := conn.Write([]byte{5, method}); err != nil {
+		return req, err
+	}
+
+	if method == 2 {
+		readString := func() (string, error) {
+			size := make([]byte, 1)
+			if _, err := io.ReadFull(conn, size); err != nil {
+				return "", err
+			}
+			s := make([]byte, size[0])
+			_, err := io.ReadFull(conn, s)
+			return string(s), err
+		}
+
+		version := make([]byte, 1)
+		if _, err := io.ReadFull(conn, version); err != nil {
+			return req, err
+		}
+		var err error
+		if req.User, err = readString(); err != nil {
+			return req, err
+		}
+		if req.Password, err = readString(); err != nil {
+			return req, err
+		}
+		if _, err := conn.Write([]byte{1, 0}); err != nil {
+			return req, err
+		}
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return req, err
+	}
+
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return req, err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return req, err
+		}
+		name := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return req, err
+		}
+		host = string(name)
+	default:
+		return req, io.ErrUnexpectedEOF
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return req, err
+	}
+	req.Addr = net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	return req, nil
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+	server.SetResult("server.banner", "banner")
+
+	addr, err := server.Listen()
+	require.NoError(t, err)
+	proxy := newSOCKS5Proxy(t, addr)
+
+	dialer, err := electrum.NewSOCKS5Dialer(proxy.listener.Addr().String(), &electrum.SOCKS5Auth{User: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	// The host name is resolved by the proxy.
+	client, err := electrum.NewClient(context.Background(), "tcp://electrum.onion:50001", electrum.WithDialer(dialer))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "banner", banner)
+
+	assert.Equal(t, []socks5Request{{User: "user", Password: "pass", Addr: "electrum.onion:50001"}}, proxy.Requests())
+}
+
+func TestTorDialer(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	addr, err := server.Listen()
+	require.NoError(t, err)
+	proxy := newSOCKS5Proxy(t, addr)
+
+	for i := 0; i < 2; i++ {
+		dialer, err := electrum.NewTorDialer(proxy.listener.Addr().String())
+		require.NoError(t, err)
+
+		client, err := electrum.NewClient(context.Background(), "tcp://electrum.onion:50001", electrum.WithDialer(dialer))
+		require.NoError(t, err)
+		client.Shutdown()
+	}
+
+	// Every dialer is isolated on its own circuit by random credentials.
+	requests := proxy.Requests()
+	require.Len(t, requests, 2)
+	for _, req := range requests {
+		assert.Len(t, req.User, 16)
+		assert.Len(t, req.Password, 16)
+		assert.Equal(t, "electrum.onion:50001", req.Addr)
+	}
+	assert.NotEqual(t, requests[0].User, requests[1].User)
+}
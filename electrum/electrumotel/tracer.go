@@ -0,0 +1,131 @@
+// Package electrumotel traces the calls and notifications of electrum clients with OpenTelemetry.
+// It is a module of its own, so the electrum package does not depend on the OpenTelemetry SDK.
+package electrumotel
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/checksum0/go-electrum/electrum/electrumotel"
+
+// Tracer implements electrum.Hooks, starting a client span for every call, as a child of the
+// span of the caller context. Notifications produce a consumer span linked to the span of the
+// call that subscribed to them.
+type Tracer struct {
+	tracer trace.Tracer
+
+	subscriptions     map[string]trace.SpanContext
+	subscriptionsLock sync.RWMutex
+}
+
+var _ electrum.Hooks = (*Tracer)(nil)
+
+// NewTracer returns a Tracer creating spans with provider, or with the global tracer provider
+// when nil. It is passed to the clients with electrum.WithHooks().
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{
+		tracer:        provider.Tracer(instrumentationName, trace.WithSchemaURL(semconv.SchemaURL)),
+		subscriptions: make(map[string]trace.SpanContext),
+	}
+}
+
+func serverAttributes(server string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{semconv.NetSockPeerAddrKey.String(host)}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.NetSockPeerPortKey.Int(p))
+	}
+
+	return attrs
+}
+
+// OnRequestStart implements electrum.Hooks.
+func (t *Tracer) OnRequestStart(ctx context.Context, req *electrum.RequestInfo) context.Context {
+	attrs := append([]attribute.KeyValue{
+		semconv.RPCSystemKey.String("jsonrpc"),
+		semconv.RPCServiceKey.String("electrum"),
+		semconv.RPCMethodKey.String(req.Method),
+		semconv.RPCJsonrpcRequestIDKey.String(strconv.FormatUint(req.ID, 10)),
+		attribute.Int("electrum.request.size", req.Size),
+	}, serverAttributes(req.Server)...)
+
+	ctx, _ = t.tracer.Start(ctx, req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(req.Start),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx
+}
+
+// OnRequestEnd implements electrum.Hooks.
+func (t *Tracer) OnRequestEnd(ctx context.Context, req *electrum.RequestInfo, resp *electrum.ResponseInfo) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int("electrum.response.size", resp.Size))
+
+	if resp.Err != nil {
+		span.RecordError(resp.Err)
+		span.SetStatus(codes.Error, electrum.ErrorType(resp.Err))
+	} else if strings.HasSuffix(req.Method, ".subscribe") {
+		t.subscriptionsLock.Lock()
+		t.subscriptions[req.Server+" "+req.Method] = span.SpanContext()
+		t.subscriptionsLock.Unlock()
+	}
+
+	span.End()
+}
+
+// notificationSpan starts the span of a notification, linked to its subscription.
+func (t *Tracer) notificationSpan(notif *electrum.NotificationInfo) trace.Span {
+	t.subscriptionsLock.RLock()
+	subscription, ok := t.subscriptions[notif.Server+" "+notif.Method]
+	t.subscriptionsLock.RUnlock()
+
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(append([]attribute.KeyValue{
+			semconv.RPCSystemKey.String("jsonrpc"),
+			semconv.RPCServiceKey.String("electrum"),
+			semconv.RPCMethodKey.String(notif.Method),
+			attribute.Int("electrum.notification.size", notif.Size),
+		}, serverAttributes(notif.Server)...)...),
+	}
+	if ok {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: subscription}))
+	}
+
+	_, span := t.tracer.Start(context.Background(), notif.Method+" notification", opts...)
+
+	return span
+}
+
+// OnNotification implements electrum.Hooks.
+func (t *Tracer) OnNotification(notif *electrum.NotificationInfo) {
+	t.notificationSpan(notif).End()
+}
+
+// OnNotificationDropped implements electrum.Hooks.
+func (t *Tracer) OnNotificationDropped(notif *electrum.NotificationInfo) {
+	span := t.notificationSpan(notif)
+	span.SetStatus(codes.Error, "notification dropped")
+	span.End()
+}
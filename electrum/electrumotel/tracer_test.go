@@ -0,0 +1,47 @@
+package electrumotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	parentCtx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "payment")
+	defer parent.End()
+
+	req := &electrum.RequestInfo{Method: "blockchain.headers.subscribe", ID: 7, Server: "127.0.0.1:50001", Start: time.Now()}
+	ctx := tracer.OnRequestStart(parentCtx, req)
+	tracer.OnRequestEnd(ctx, req, &electrum.ResponseInfo{Size: 100})
+
+	failed := &electrum.RequestInfo{Method: "server.ping", ID: 8, Server: "127.0.0.1:50001", Start: time.Now()}
+	tracer.OnRequestEnd(tracer.OnRequestStart(parentCtx, failed), failed, &electrum.ResponseInfo{Err: electrum.ErrTimeout})
+
+	tracer.OnNotification(&electrum.NotificationInfo{Method: "blockchain.headers.subscribe", Server: "127.0.0.1:50001"})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, "blockchain.headers.subscribe", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "timeout", spans[1].Status().Description)
+
+	assert.Equal(t, trace.SpanKindConsumer, spans[2].SpanKind())
+	require.Len(t, spans[2].Links(), 1)
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[2].Links()[0].SpanContext.SpanID())
+}
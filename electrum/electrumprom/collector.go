@@ -0,0 +1,133 @@
+// Package electrumprom exports the requests and notifications of electrum clients as
+// Prometheus metrics. It is a module of its own, so the electrum package does not depend
+// on the Prometheus client.
+package electrumprom
+
+import (
+	"context"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements electrum.Hooks and prometheus.Collector. A single Collector can
+// instrument several clients, for instance every client of a pool, the remote server being
+// recorded in the server label.
+type Collector struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	inFlight      *prometheus.GaugeVec
+	sentBytes     *prometheus.CounterVec
+	receivedBytes *prometheus.CounterVec
+	notifications *prometheus.CounterVec
+	dropped       *prometheus.CounterVec
+}
+
+var _ electrum.Hooks = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a Collector with metrics named namespace_electrum_*. It must be
+// registered with a prometheus.Registerer, and passed to the clients with electrum.WithHooks().
+func NewCollector(namespace string) *Collector {
+	const subsystem = "electrum"
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Requests sent to the remote server, by method and error type.",
+		}, []string{"server", "method", "error"}),
+
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the requests sent to the remote server.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"server", "method"}),
+
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_in_flight",
+			Help:      "Requests waiting for an answer from the remote server.",
+		}, []string{"server", "method"}),
+
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_bytes_total",
+			Help:      "Size of the requests sent to the remote server.",
+		}, []string{"server", "method"}),
+
+		receivedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "response_bytes_total",
+			Help:      "Size of the responses received from the remote server.",
+		}, []string{"server", "method"}),
+
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "notifications_total",
+			Help:      "Notifications received from the remote server.",
+		}, []string{"server", "method"}),
+
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "notifications_dropped_total",
+			Help:      "Notifications dropped because a subscription did not keep up.",
+		}, []string{"server", "method"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.inFlight.Describe(ch)
+	c.sentBytes.Describe(ch)
+	c.receivedBytes.Describe(ch)
+	c.notifications.Describe(ch)
+	c.dropped.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.inFlight.Collect(ch)
+	c.sentBytes.Collect(ch)
+	c.receivedBytes.Collect(ch)
+	c.notifications.Collect(ch)
+	c.dropped.Collect(ch)
+}
+
+// OnRequestStart implements electrum.Hooks.
+func (c *Collector) OnRequestStart(ctx context.Context, req *electrum.RequestInfo) context.Context {
+	c.inFlight.WithLabelValues(req.Server, req.Method).Inc()
+	c.sentBytes.WithLabelValues(req.Server, req.Method).Add(float64(req.Size))
+
+	return ctx
+}
+
+// OnRequestEnd implements electrum.Hooks.
+func (c *Collector) OnRequestEnd(ctx context.Context, req *electrum.RequestInfo, resp *electrum.ResponseInfo) {
+	c.inFlight.WithLabelValues(req.Server, req.Method).Dec()
+	c.requests.WithLabelValues(req.Server, req.Method, electrum.ErrorType(resp.Err)).Inc()
+	c.duration.WithLabelValues(req.Server, req.Method).Observe(resp.Latency.Seconds())
+	c.receivedBytes.WithLabelValues(req.Server, req.Method).Add(float64(resp.Size))
+}
+
+// OnNotification implements electrum.Hooks.
+func (c *Collector) OnNotification(notif *electrum.NotificationInfo) {
+	c.notifications.WithLabelValues(notif.Server, notif.Method).Inc()
+}
+
+// OnNotificationDropped implements electrum.Hooks.
+func (c *Collector) OnNotificationDropped(notif *electrum.NotificationInfo) {
+	c.dropped.WithLabelValues(notif.Server, notif.Method).Inc()
+}
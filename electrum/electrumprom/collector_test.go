@@ -0,0 +1,36 @@
+package electrumprom
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector("test")
+
+	req := &electrum.RequestInfo{Method: "server.ping", Server: "127.0.0.1:50001", Size: 50}
+	ctx := c.OnRequestStart(context.Background(), req)
+	c.OnRequestEnd(ctx, req, &electrum.ResponseInfo{Latency: time.Millisecond, Err: electrum.ErrTimeout})
+	c.OnNotificationDropped(&electrum.NotificationInfo{Method: "blockchain.headers.subscribe", Server: "127.0.0.1:50001"})
+
+	expected := `
+# HELP test_electrum_requests_total Requests sent to the remote server, by method and error type.
+# TYPE test_electrum_requests_total counter
+test_electrum_requests_total{error="timeout",method="server.ping",server="127.0.0.1:50001"} 1
+# HELP test_electrum_notifications_dropped_total Notifications dropped because a subscription did not keep up.
+# TYPE test_electrum_notifications_dropped_total counter
+test_electrum_notifications_dropped_total{method="blockchain.headers.subscribe",server="127.0.0.1:50001"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"test_electrum_requests_total", "test_electrum_notifications_dropped_total")
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(c.inFlight))
+}
@@ -0,0 +1,63 @@
+package electrumserver
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/checksum0/go-electrum/electrum"
+)
+
+// Backend provides the chain and mempool data served to the clients. Errors of type
+// *electrum.RPCError are sent to the client as is, *electrum.TxRejectedError as a rejected
+// broadcast, and any other error with the electrum.CodeDaemonError code.
+type Backend interface {
+	// Tip returns the height and the header of the chain tip.
+	Tip(ctx context.Context) (int32, *wire.BlockHeader, error)
+
+	// Headers returns up to count consecutive headers starting at height start, fewer past
+	// the tip.
+	Headers(ctx context.Context, start, count uint32) ([]*wire.BlockHeader, error)
+
+	// Balance returns the confirmed and unconfirmed balance of a scripthash.
+	Balance(ctx context.Context, scripthash string) (electrum.GetBalanceResult, error)
+
+	// History returns the confirmed transactions touching a scripthash in chain order,
+	// followed by its mempool transactions.
+	History(ctx context.Context, scripthash string) ([]*electrum.GetMempoolResult, error)
+
+	// Mempool returns the mempool transactions touching a scripthash.
+	Mempool(ctx context.Context, scripthash string) ([]*electrum.GetMempoolResult, error)
+
+	// ListUnspent returns the unspent outputs paying to a scripthash.
+	ListUnspent(ctx context.Context, scripthash string) ([]*electrum.ListUnspentResult, error)
+
+	// Transaction returns the raw transaction txid.
+	Transaction(ctx context.Context, txid string) ([]byte, error)
+
+	// Broadcast relays a raw transaction and returns its hash.
+	Broadcast(ctx context.Context, rawTx []byte) (string, error)
+
+	// MerkleProof returns the merkle branch of the transaction txid in the block at height.
+	MerkleProof(ctx context.Context, txid string, height uint32) (*electrum.GetMerkleProofResult, error)
+
+	// TxIDFromPos returns the hash of the transaction at pos in the block at height, and
+	// its merkle branch.
+	TxIDFromPos(ctx context.Context, height, pos uint32) (*electrum.GetMerkleProofFromPosResult, error)
+
+	// EstimateFee returns the fee rate in BTC/kB for a transaction to be confirmed within
+	// blocks, or -1 without estimate.
+	EstimateFee(ctx context.Context, blocks uint32) (float64, error)
+
+	// RelayFee returns the minimum fee rate in BTC/kB accepted into the mempool.
+	RelayFee(ctx context.Context) (float64, error)
+
+	// FeeHistogram returns the [fee rate, vsize] pairs of the mempool, by decreasing fee rate.
+	FeeHistogram(ctx context.Context) ([][2]uint64, error)
+}
+
+// VerboseBackend is implemented by the backends able to describe transactions, as required
+// by verbose blockchain.transaction.get calls.
+type VerboseBackend interface {
+	VerboseTransaction(ctx context.Context, txid string) (*electrum.GetTransactionResult, error)
+}
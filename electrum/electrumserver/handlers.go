@@ -0,0 +1,664 @@
+package electrumserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/checksum0/go-electrum/electrum"
+)
+
+const (
+	// codeParseError is the JSON-RPC code of messages that are not valid JSON.
+	codeParseError = -32700
+
+	// headersChunkMax is the maximum number of headers returned by blockchain.block.headers.
+	headersChunkMax = 2016
+)
+
+type request struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string             `json:"jsonrpc"`
+	ID      json.RawMessage    `json:"id"`
+	Result  interface{}        `json:"result,omitempty"`
+	Error   *electrum.RPCError `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// handler answers a call of a session.
+type handler func(sess *session, params []json.RawMessage) (interface{}, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"blockchain.block.header":            (*session).blockHeader,
+		"blockchain.block.headers":           (*session).blockHeaders,
+		"blockchain.estimatefee":             (*session).estimateFee,
+		"blockchain.headers.subscribe":       (*session).headersSubscribe,
+		"blockchain.relayfee":                (*session).relayFee,
+		"blockchain.scripthash.get_balance":  (*session).getBalance,
+		"blockchain.scripthash.get_history":  (*session).getHistory,
+		"blockchain.scripthash.get_mempool":  (*session).getMempool,
+		"blockchain.scripthash.listunspent":  (*session).listUnspent,
+		"blockchain.scripthash.subscribe":    (*session).scripthashSubscribe,
+		"blockchain.scripthash.unsubscribe":  (*session).scripthashUnsubscribe,
+		"blockchain.transaction.broadcast":   (*session).broadcast,
+		"blockchain.transaction.get":         (*session).getTransaction,
+		"blockchain.transaction.get_merkle":  (*session).getMerkle,
+		"blockchain.transaction.id_from_pos": (*session).idFromPos,
+		"mempool.get_fee_histogram":          (*session).feeHistogram,
+		"server.add_peer":                    (*session).addPeer,
+		"server.banner":                      (*session).banner,
+		"server.donation_address":            (*session).donationAddress,
+		"server.features":                    (*session).features,
+		"server.peers.subscribe":             (*session).peers,
+		"server.ping":                        (*session).ping,
+		"server.version":                     (*session).serverVersion,
+	}
+}
+
+// handle answers a received message, holding a single call or a batch of calls.
+func (sess *session) handle(msg []byte) {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 {
+		return
+	}
+
+	if msg[0] == '[' {
+		var reqs []*request
+		if err := json.Unmarshal(msg, &reqs); err != nil {
+			sess.send(errorResponse(nil, &electrum.RPCError{Code: codeParseError, Message: err.Error()}))
+			return
+		}
+		if len(reqs) == 0 {
+			sess.send(errorResponse(nil, &electrum.RPCError{Code: electrum.CodeInvalidRequest, Message: "empty batch"}))
+			return
+		}
+
+		resps := make([]*response, 0, len(reqs))
+		for _, req := range reqs {
+			if resp := sess.call(req); resp != nil {
+				resps = append(resps, resp)
+			}
+		}
+		if len(resps) > 0 {
+			sess.send(resps)
+		}
+
+		return
+	}
+
+	req := &request{}
+	if err := json.Unmarshal(msg, req); err != nil {
+		sess.send(errorResponse(nil, &electrum.RPCError{Code: codeParseError, Message: err.Error()}))
+		return
+	}
+
+	if resp := sess.call(req); resp != nil {
+		sess.send(resp)
+	}
+}
+
+// call runs a single call, and returns its response, or nil for JSON-RPC notifications.
+func (sess *session) call(req *request) *response {
+	h, ok := handlers[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return errorResponse(req.ID, &electrum.RPCError{
+			Code:    electrum.CodeMethodNotFound,
+			Message: "unknown method " + strconv.Quote(req.Method),
+		})
+	}
+
+	result, err := h(sess, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		sess.server.log(electrum.LogDebug, "call failed", electrum.LogField{Key: "method", Value: req.Method},
+			electrum.LogField{Key: "remote", Value: sess.conn.remoteAddr()}, electrum.LogField{Key: "error", Value: err})
+		return errorResponse(req.ID, rpcError(err))
+	}
+
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: nullable(result)}
+}
+
+func errorResponse(id json.RawMessage, err *electrum.RPCError) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// nullResult is sent as a null result, which omitempty would otherwise leave out.
+type nullResult struct{}
+
+func (nullResult) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func nullable(result interface{}) interface{} {
+	if result == nil {
+		return nullResult{}
+	}
+
+	return result
+}
+
+// rpcError converts an error returned by the backend to the error sent to the client.
+func rpcError(err error) *electrum.RPCError {
+	var rejected *electrum.TxRejectedError
+	if errors.As(err, &rejected) && rejected.Err == nil {
+		return &electrum.RPCError{
+			Code:    electrum.CodeBadRequest,
+			Message: "the transaction was rejected by network rules.\n\n" + rejected.Reason,
+		}
+	}
+
+	var rpcErr *electrum.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	return &electrum.RPCError{Code: electrum.CodeDaemonError, Message: err.Error()}
+}
+
+// unmarshalParams decodes the params of a call into v, the missing trailing params being
+// left untouched.
+func unmarshalParams(params []json.RawMessage, v ...interface{}) error {
+	for i, param := range params {
+		if i >= len(v) {
+			break
+		}
+
+		err := json.Unmarshal(param, v[i])
+		if err != nil {
+			return &electrum.RPCError{Code: electrum.CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &electrum.RPCError{Code: electrum.CodeBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+func headerHex(header *wire.BlockHeader) string {
+	var buf bytes.Buffer
+	_ = header.Serialize(&buf)
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// statusResult returns the JSON value of a status, null without history.
+func statusResult(status string) interface{} {
+	if status == "" {
+		return nil
+	}
+
+	return status
+}
+
+// compareVersions compares two dotted protocol versions, missing components being 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (sess *session) serverVersion(params []json.RawMessage) (interface{}, error) {
+	if sess.versionSent {
+		return nil, badRequest("server.version already sent")
+	}
+
+	var clientName string
+	var version json.RawMessage
+	if err := unmarshalParams(params, &clientName, &version); err != nil {
+		return nil, err
+	}
+
+	min, max := electrum.ProtocolVersionMin, electrum.ProtocolVersionMin
+	if len(version) > 0 {
+		var versions []string
+		if json.Unmarshal(version, &max) == nil {
+			min = max
+		} else if json.Unmarshal(version, &versions) == nil && len(versions) == 2 {
+			min, max = versions[0], versions[1]
+		} else {
+			return nil, &electrum.RPCError{Code: electrum.CodeInvalidParams, Message: "invalid protocol version"}
+		}
+	}
+
+	negotiated := electrum.ProtocolVersion
+	if compareVersions(max, negotiated) < 0 {
+		negotiated = max
+	}
+	if compareVersions(negotiated, min) < 0 || compareVersions(negotiated, electrum.ProtocolVersionMin) < 0 {
+		return nil, badRequest("unsupported protocol version: %s", version)
+	}
+
+	sess.versionSent = true
+
+	return []string{sess.server.version, negotiated}, nil
+}
+
+func (sess *session) ping([]json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (sess *session) banner([]json.RawMessage) (interface{}, error) {
+	return sess.server.banner, nil
+}
+
+func (sess *session) donationAddress([]json.RawMessage) (interface{}, error) {
+	return sess.server.donationAddress, nil
+}
+
+func (sess *session) addPeer([]json.RawMessage) (interface{}, error) {
+	return false, nil
+}
+
+func (sess *session) peers([]json.RawMessage) (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+type features struct {
+	GenesisHash   string          `json:"genesis_hash"`
+	Hosts         map[string]Host `json:"hosts"`
+	ProtocolMax   string          `json:"protocol_max"`
+	ProtocolMin   string          `json:"protocol_min"`
+	Pruning       interface{}     `json:"pruning"`
+	ServerVersion string          `json:"server_version"`
+	HashFunction  string          `json:"hash_function"`
+}
+
+func (sess *session) features([]json.RawMessage) (interface{}, error) {
+	headers, err := sess.server.backend.Headers(sess.ctx, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return nil, badRequest("genesis block not found")
+	}
+
+	hosts := sess.server.hosts
+	if hosts == nil {
+		hosts = map[string]Host{}
+	}
+
+	return &features{
+		GenesisHash:   headers[0].BlockHash().String(),
+		Hosts:         hosts,
+		ProtocolMax:   electrum.ProtocolVersion,
+		ProtocolMin:   electrum.ProtocolVersionMin,
+		ServerVersion: sess.server.version,
+		HashFunction:  "sha256",
+	}, nil
+}
+
+func (sess *session) headersSubscribe([]json.RawMessage) (interface{}, error) {
+	height, header, err := sess.server.backend.Tip(sess.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.lock.Lock()
+	sess.headers = true
+	sess.lock.Unlock()
+
+	return &electrum.SubscribeHeadersResult{Height: height, Hex: headerHex(header)}, nil
+}
+
+// checkpointProof returns the merkle root of the block hashes up to cpHeight, and the branch
+// proving the block hash at height.
+func checkpointProof(ctx context.Context, backend Backend, height, cpHeight uint32) ([]string, string, error) {
+	if height > cpHeight {
+		return nil, "", badRequest("header height %d must be <= cp_height %d", height, cpHeight)
+	}
+
+	leaves := make([]chainhash.Hash, 0, cpHeight+1)
+	for start := uint32(0); start <= cpHeight; {
+		count := cpHeight - start + 1
+		if count > headersChunkMax {
+			count = headersChunkMax
+		}
+
+		headers, err := backend.Headers(ctx, start, count)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(headers) == 0 {
+			return nil, "", badRequest("cp_height %d above chain height", cpHeight)
+		}
+
+		for _, header := range headers {
+			leaves = append(leaves, header.BlockHash())
+		}
+		start += uint32(len(headers))
+	}
+
+	branch, root := electrum.MerkleBranch(leaves, height)
+
+	return branch, root.String(), nil
+}
+
+func (sess *session) blockHeader(params []json.RawMessage) (interface{}, error) {
+	var height, cpHeight uint32
+	if err := unmarshalParams(params, &height, &cpHeight); err != nil {
+		return nil, err
+	}
+
+	headers, err := sess.server.backend.Headers(sess.ctx, height, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return nil, badRequest("height %d out of range", height)
+	}
+
+	header := headerHex(headers[0])
+	if cpHeight == 0 {
+		return header, nil
+	}
+
+	branch, root, err := checkpointProof(sess.ctx, sess.server.backend, height, cpHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &electrum.GetBlockHeaderResult{Branch: branch, Header: header, Root: root}, nil
+}
+
+func (sess *session) blockHeaders(params []json.RawMessage) (interface{}, error) {
+	var start, count, cpHeight uint32
+	if err := unmarshalParams(params, &start, &count, &cpHeight); err != nil {
+		return nil, err
+	}
+	if count > headersChunkMax {
+		count = headersChunkMax
+	}
+
+	var headers []*wire.BlockHeader
+	if count > 0 {
+		var err error
+		headers, err = sess.server.backend.Headers(sess.ctx, start, count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf strings.Builder
+	for _, header := range headers {
+		buf.WriteString(headerHex(header))
+	}
+
+	result := &electrum.GetBlockHeadersResult{Count: uint32(len(headers)), Headers: buf.String(), Max: headersChunkMax}
+	if cpHeight != 0 && len(headers) > 0 {
+		branch, root, err := checkpointProof(sess.ctx, sess.server.backend, start+result.Count-1, cpHeight)
+		if err != nil {
+			return nil, err
+		}
+		result.Branch, result.Root = branch, root
+	}
+
+	return result, nil
+}
+
+func (sess *session) estimateFee(params []json.RawMessage) (interface{}, error) {
+	var blocks uint32
+	if err := unmarshalParams(params, &blocks); err != nil {
+		return nil, err
+	}
+
+	return sess.server.backend.EstimateFee(sess.ctx, blocks)
+}
+
+func (sess *session) relayFee([]json.RawMessage) (interface{}, error) {
+	return sess.server.backend.RelayFee(sess.ctx)
+}
+
+func (sess *session) feeHistogram([]json.RawMessage) (interface{}, error) {
+	histogram, err := sess.server.backend.FeeHistogram(sess.ctx)
+	if err != nil {
+		return nil, err
+	}
+	if histogram == nil {
+		histogram = [][2]uint64{}
+	}
+
+	return histogram, nil
+}
+
+func scripthashParam(params []json.RawMessage) (string, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return "", err
+	}
+
+	if raw, err := hex.DecodeString(scripthash); err != nil || len(raw) != sha256.Size {
+		return "", badRequest("%s is not a valid script hash", strconv.Quote(scripthash))
+	}
+
+	return scripthash, nil
+}
+
+func (sess *session) getBalance(params []json.RawMessage) (interface{}, error) {
+	scripthash, err := scripthashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return sess.server.backend.Balance(sess.ctx, scripthash)
+}
+
+func nonNil(history []*electrum.GetMempoolResult) []*electrum.GetMempoolResult {
+	if history == nil {
+		return []*electrum.GetMempoolResult{}
+	}
+
+	return history
+}
+
+func (sess *session) getHistory(params []json.RawMessage) (interface{}, error) {
+	scripthash, err := scripthashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := sess.server.backend.History(sess.ctx, scripthash)
+	if err != nil {
+		return nil, err
+	}
+
+	return nonNil(history), nil
+}
+
+func (sess *session) getMempool(params []json.RawMessage) (interface{}, error) {
+	scripthash, err := scripthashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	mempool, err := sess.server.backend.Mempool(sess.ctx, scripthash)
+	if err != nil {
+		return nil, err
+	}
+
+	return nonNil(mempool), nil
+}
+
+func (sess *session) listUnspent(params []json.RawMessage) (interface{}, error) {
+	scripthash, err := scripthashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	unspent, err := sess.server.backend.ListUnspent(sess.ctx, scripthash)
+	if err != nil {
+		return nil, err
+	}
+	if unspent == nil {
+		unspent = []*electrum.ListUnspentResult{}
+	}
+
+	return unspent, nil
+}
+
+func (sess *session) scripthashSubscribe(params []json.RawMessage) (interface{}, error) {
+	scripthash, err := scripthashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := sess.server.backend.History(sess.ctx, scripthash)
+	if err != nil {
+		return nil, err
+	}
+	status := electrum.ScripthashStatus(history)
+
+	sess.lock.Lock()
+	_, ok := sess.scripthashes[scripthash]
+	if !ok && len(sess.scripthashes) >= sess.server.maxSubscriptions {
+		sess.lock.Unlock()
+		return nil, badRequest("too many scripthash subscriptions, at most %d", sess.server.maxSubscriptions)
+	}
+	sess.scripthashes[scripthash] = status
+	sess.lock.Unlock()
+
+	return statusResult(status), nil
+}
+
+func (sess *session) scripthashUnsubscribe(params []json.RawMessage) (interface{}, error) {
+	scripthash, err := scripthashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.lock.Lock()
+	_, ok := sess.scripthashes[scripthash]
+	delete(sess.scripthashes, scripthash)
+	sess.lock.Unlock()
+
+	return ok, nil
+}
+
+func (sess *session) broadcast(params []json.RawMessage) (interface{}, error) {
+	var rawTx string
+	if err := unmarshalParams(params, &rawTx); err != nil {
+		return nil, err
+	}
+
+	raw, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, badRequest("the transaction was rejected by network rules.\n\nTX decode failed\n[%s]", rawTx)
+	}
+
+	return sess.server.backend.Broadcast(sess.ctx, raw)
+}
+
+func txidParam(txid string) error {
+	if _, err := chainhash.NewHashFromStr(txid); err != nil || len(txid) != 2*chainhash.HashSize {
+		return badRequest("%s should be a transaction hash", strconv.Quote(txid))
+	}
+
+	return nil
+}
+
+func (sess *session) getTransaction(params []json.RawMessage) (interface{}, error) {
+	var txid string
+	var verbose bool
+	if err := unmarshalParams(params, &txid, &verbose); err != nil {
+		return nil, err
+	}
+	if err := txidParam(txid); err != nil {
+		return nil, err
+	}
+
+	if verbose {
+		backend, ok := sess.server.backend.(VerboseBackend)
+		if !ok {
+			return nil, badRequest("verbose transactions are not supported")
+		}
+
+		return backend.VerboseTransaction(sess.ctx, txid)
+	}
+
+	raw, err := sess.server.backend.Transaction(sess.ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.EncodeToString(raw), nil
+}
+
+func (sess *session) getMerkle(params []json.RawMessage) (interface{}, error) {
+	var txid string
+	var height uint32
+	if err := unmarshalParams(params, &txid, &height); err != nil {
+		return nil, err
+	}
+	if err := txidParam(txid); err != nil {
+		return nil, err
+	}
+
+	return sess.server.backend.MerkleProof(sess.ctx, txid, height)
+}
+
+func (sess *session) idFromPos(params []json.RawMessage) (interface{}, error) {
+	var height, pos uint32
+	var merkle bool
+	if err := unmarshalParams(params, &height, &pos, &merkle); err != nil {
+		return nil, err
+	}
+
+	result, err := sess.server.backend.TxIDFromPos(sess.ctx, height, pos)
+	if err != nil {
+		return nil, err
+	}
+	if !merkle {
+		return result.Hash, nil
+	}
+
+	return result, nil
+}
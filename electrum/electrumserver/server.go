@@ -0,0 +1,499 @@
+// Package electrumserver implements the server side of the Electrum protocol over TCP, TLS
+// and WebSocket, serving the data of a pluggable Backend, to build Electrum compatible
+// front ends of custom indexers.
+package electrumserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gorilla/websocket"
+
+	"github.com/checksum0/go-electrum/electrum"
+)
+
+const (
+	// ServerVersion identifies the server software to the clients, see WithServerVersion().
+	ServerVersion = "go-electrum-server1.1"
+
+	// DefaultMaxSubscriptions is the number of scripthashes a session may subscribe to, see
+	// WithMaxSubscriptions().
+	DefaultMaxSubscriptions = 10000
+
+	// maxLineSize bounds the size of a received message.
+	maxLineSize = 4 << 20
+
+	// sendQueueSize bounds the messages waiting to be written to a session. A session
+	// falling that far behind is too slow to keep up with its notifications and is
+	// disconnected.
+	sendQueueSize = 256
+
+	// writeTimeout bounds the write of a single message to a session.
+	writeTimeout = 30 * time.Second
+)
+
+var (
+	// ErrServerClosed throws an error if a closed server is asked to serve connections.
+	ErrServerClosed = errors.New("electrumserver: server closed")
+)
+
+// Host describes the ports a server can be reached on under a host name, as announced by
+// server.features.
+type Host struct {
+	TCPPort uint16 `json:"tcp_port,omitempty"`
+	SSLPort uint16 `json:"ssl_port,omitempty"`
+}
+
+// Option configures a server created with NewServer().
+type Option func(*Server)
+
+// WithServerVersion sets the server software version sent to the clients, ServerVersion
+// by default.
+func WithServerVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// WithBanner sets the answer to server.banner.
+func WithBanner(banner string) Option {
+	return func(s *Server) {
+		s.banner = banner
+	}
+}
+
+// WithDonationAddress sets the answer to server.donation_address.
+func WithDonationAddress(address string) Option {
+	return func(s *Server) {
+		s.donationAddress = address
+	}
+}
+
+// WithHosts sets the hosts announced by server.features.
+func WithHosts(hosts map[string]Host) Option {
+	return func(s *Server) {
+		s.hosts = hosts
+	}
+}
+
+// WithMaxSubscriptions sets the number of scripthashes a session may subscribe to,
+// DefaultMaxSubscriptions by default. Subscriptions above it fail with a bad request error.
+func WithMaxSubscriptions(n int) Option {
+	return func(s *Server) {
+		s.maxSubscriptions = n
+	}
+}
+
+// WithLogger writes the log entries of the server to logger.
+func WithLogger(logger electrum.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// Server serves the Electrum protocol to its clients from a Backend. Every connection is a
+// session, holding its own header and scripthash subscriptions. The backend reports changes
+// with NotifyHeader() and NotifyScripthash(), which push the notifications to the sessions
+// subscribed to them. Messages are queued to each session without waiting for the writes,
+// the sessions not reading them fast enough being disconnected.
+type Server struct {
+	backend Backend
+
+	version         string
+	banner          string
+	donationAddress string
+	hosts           map[string]Host
+	logger          electrum.Logger
+
+	maxSubscriptions int
+
+	upgrader websocket.Upgrader
+
+	sessions  map[*session]struct{}
+	listeners map[net.Listener]struct{}
+	closed    bool
+	lock      sync.Mutex
+}
+
+// NewServer returns a server answering from backend, configured by opts.
+func NewServer(backend Backend, opts ...Option) *Server {
+	s := &Server{
+		backend:          backend,
+		version:          ServerVersion,
+		maxSubscriptions: DefaultMaxSubscriptions,
+		sessions:         make(map[*session]struct{}),
+		listeners:        make(map[net.Listener]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ListenAndServe accepts TCP connections on addr, see Serve().
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// ListenAndServeTLS accepts TLS connections on addr, see Serve().
+func (s *Server) ListenAndServeTLS(addr string, config *tls.Config) error {
+	listener, err := tls.Listen("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener and serves each of them in its own goroutine, until
+// the listener fails or the server is closed. It always returns a non-nil error, ErrServerClosed
+// after Close().
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.listeners, listener)
+		s.lock.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	sess := s.newSession(&streamConn{conn: conn})
+	if sess == nil {
+		return
+	}
+	defer s.endSession(sess)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		sess.handle(scanner.Bytes())
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves it, each message
+// holding a single call or batch.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(maxLineSize)
+
+	sess := s.newSession(&wsConn{conn: conn})
+	if sess == nil {
+		return
+	}
+	defer s.endSession(sess)
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		sess.handle(msg)
+	}
+}
+
+// Close stops the listeners and closes every session.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	listeners := s.listeners
+	s.listeners = make(map[net.Listener]struct{})
+	s.lock.Unlock()
+
+	for listener := range listeners {
+		_ = listener.Close()
+	}
+	for _, sess := range s.connected() {
+		sess.close()
+	}
+
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.closed
+}
+
+func (s *Server) newSession(c conn) *session {
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := &session{
+		server:       s,
+		conn:         c,
+		ctx:          ctx,
+		cancel:       cancel,
+		scripthashes: make(map[string]string),
+		queue:        make(chan []byte, sendQueueSize),
+	}
+
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		_ = c.close()
+		return nil
+	}
+	s.sessions[sess] = struct{}{}
+	s.lock.Unlock()
+
+	go sess.writeLoop()
+
+	s.log(electrum.LogDebug, "session opened", electrum.LogField{Key: "remote", Value: c.remoteAddr()})
+
+	return sess
+}
+
+func (s *Server) endSession(sess *session) {
+	s.lock.Lock()
+	delete(s.sessions, sess)
+	s.lock.Unlock()
+
+	sess.close()
+	s.log(electrum.LogDebug, "session closed", electrum.LogField{Key: "remote", Value: sess.conn.remoteAddr()})
+}
+
+func (s *Server) connected() []*session {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+
+	return sessions
+}
+
+func (s *Server) log(level electrum.LogLevel, msg string, fields ...electrum.LogField) {
+	if s.logger != nil {
+		s.logger.Log(level, msg, fields...)
+	}
+}
+
+// NotifyHeader pushes the new chain tip to the sessions subscribed to headers.
+func (s *Server) NotifyHeader(height int32, header *wire.BlockHeader) {
+	result := &electrum.SubscribeHeadersResult{Height: height, Hex: headerHex(header)}
+
+	for _, sess := range s.connected() {
+		if sess.subscribedHeaders() {
+			sess.notify("blockchain.headers.subscribe", result)
+		}
+	}
+}
+
+// NotifyScripthash pushes the new status of scripthashes to the sessions subscribed to them,
+// when it changed since their last notification.
+func (s *Server) NotifyScripthash(ctx context.Context, scripthashes ...string) error {
+	sessions := s.connected()
+
+	for _, scripthash := range scripthashes {
+		var subscribers []*session
+		for _, sess := range sessions {
+			if sess.subscribed(scripthash) {
+				subscribers = append(subscribers, sess)
+			}
+		}
+		if len(subscribers) == 0 {
+			continue
+		}
+
+		history, err := s.backend.History(ctx, scripthash)
+		if err != nil {
+			return err
+		}
+		status := electrum.ScripthashStatus(history)
+
+		for _, sess := range subscribers {
+			if sess.updateStatus(scripthash, status) {
+				sess.notify("blockchain.scripthash.subscribe", scripthash, statusResult(status))
+			}
+		}
+	}
+
+	return nil
+}
+
+// conn is the connection of a session.
+type conn interface {
+	write(msg []byte) error
+	close() error
+	remoteAddr() string
+}
+
+// streamConn is a TCP or TLS connection, messages being delimited by newlines.
+type streamConn struct {
+	conn net.Conn
+}
+
+func (c *streamConn) write(msg []byte) error {
+	err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.conn.Write(append(msg, '\n'))
+
+	return err
+}
+
+func (c *streamConn) close() error {
+	return c.conn.Close()
+}
+
+func (c *streamConn) remoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+// wsConn is a WebSocket connection, a message being sent in each frame.
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func (c *wsConn) write(msg []byte) error {
+	err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err != nil {
+		return err
+	}
+
+	return c.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+func (c *wsConn) close() error {
+	return c.conn.Close()
+}
+
+func (c *wsConn) remoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+// session is a client connected to the server.
+type session struct {
+	server *Server
+	conn   conn
+
+	// ctx is cancelled when the session is closed, interrupting its backend calls.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// versionSent is only accessed by the goroutine reading the session.
+	versionSent bool
+
+	headers      bool
+	scripthashes map[string]string
+	lock         sync.Mutex
+
+	// queue holds the messages waiting to be written by writeLoop().
+	queue chan []byte
+}
+
+// send queues v to be written to the session, disconnecting the session when its queue is full.
+func (sess *session) send(v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		sess.server.log(electrum.LogError, "marshal message failed", electrum.LogField{Key: "error", Value: err})
+		return
+	}
+
+	select {
+	case <-sess.ctx.Done():
+	case sess.queue <- msg:
+	default:
+		sess.server.log(electrum.LogWarn, "session too slow, disconnecting",
+			electrum.LogField{Key: "remote", Value: sess.conn.remoteAddr()})
+		sess.close()
+	}
+}
+
+// writeLoop writes the queued messages to the session until it is closed.
+func (sess *session) writeLoop() {
+	for {
+		select {
+		case <-sess.ctx.Done():
+			return
+		case msg := <-sess.queue:
+			if err := sess.conn.write(msg); err != nil {
+				sess.close()
+				return
+			}
+		}
+	}
+}
+
+func (sess *session) notify(method string, params ...interface{}) {
+	sess.send(&notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (sess *session) close() {
+	sess.cancel()
+	_ = sess.conn.close()
+}
+
+func (sess *session) subscribedHeaders() bool {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	return sess.headers
+}
+
+func (sess *session) subscribed(scripthash string) bool {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	_, ok := sess.scripthashes[scripthash]
+
+	return ok
+}
+
+// updateStatus records the status of a subscribed scripthash, and reports whether it changed.
+func (sess *session) updateStatus(scripthash, status string) bool {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	last, ok := sess.scripthashes[scripthash]
+	if !ok || last == status {
+		return false
+	}
+	sess.scripthashes[scripthash] = status
+
+	return true
+}
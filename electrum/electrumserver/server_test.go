@@ -0,0 +1,315 @@
+package electrumserver_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScripthash = "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"
+
+var errNotFound = errors.New("not found")
+
+// backend is an in-memory Backend holding a header chain and the history of a single scripthash.
+type backend struct {
+	headers []*wire.BlockHeader
+	history []*electrum.GetMempoolResult
+	lock    sync.Mutex
+}
+
+func newBackend(n int) *backend {
+	b := &backend{}
+	for i := 0; i < n; i++ {
+		b.mine()
+	}
+
+	return b
+}
+
+func (b *backend) mine() (int32, *wire.BlockHeader) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	header := &wire.BlockHeader{Version: 4, Timestamp: time.Unix(1600000000+int64(len(b.headers))*600, 0)}
+	if len(b.headers) > 0 {
+		header.PrevBlock = b.headers[len(b.headers)-1].BlockHash()
+	}
+	b.headers = append(b.headers, header)
+
+	return int32(len(b.headers) - 1), header
+}
+
+func (b *backend) Tip(context.Context) (int32, *wire.BlockHeader, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return int32(len(b.headers) - 1), b.headers[len(b.headers)-1], nil
+}
+
+func (b *backend) Headers(_ context.Context, start, count uint32) ([]*wire.BlockHeader, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if int(start) >= len(b.headers) {
+		return nil, nil
+	}
+	end := int(start + count)
+	if end > len(b.headers) {
+		end = len(b.headers)
+	}
+
+	return b.headers[start:end], nil
+}
+
+func (b *backend) Balance(context.Context, string) (electrum.GetBalanceResult, error) {
+	return electrum.GetBalanceResult{Confirmed: 1000}, nil
+}
+
+func (b *backend) History(_ context.Context, scripthash string) ([]*electrum.GetMempoolResult, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if scripthash != testScripthash {
+		return nil, nil
+	}
+
+	return append([]*electrum.GetMempoolResult(nil), b.history...), nil
+}
+
+func (b *backend) Mempool(context.Context, string) ([]*electrum.GetMempoolResult, error) {
+	return nil, nil
+}
+
+func (b *backend) ListUnspent(context.Context, string) ([]*electrum.ListUnspentResult, error) {
+	return nil, nil
+}
+
+func (b *backend) Transaction(context.Context, string) ([]byte, error) {
+	return nil, &electrum.RPCError{Code: electrum.CodeDaemonError, Message: "No such mempool or blockchain transaction."}
+}
+
+func (b *backend) Broadcast(context.Context, []byte) (string, error) {
+	return "", &electrum.TxRejectedError{Reason: "min relay fee not met"}
+}
+
+func (b *backend) MerkleProof(context.Context, string, uint32) (*electrum.GetMerkleProofResult, error) {
+	return nil, errNotFound
+}
+
+func (b *backend) TxIDFromPos(context.Context, uint32, uint32) (*electrum.GetMerkleProofFromPosResult, error) {
+	return nil, errNotFound
+}
+
+func (b *backend) EstimateFee(context.Context, uint32) (float64, error) {
+	return 0.0002, nil
+}
+
+func (b *backend) RelayFee(context.Context) (float64, error) {
+	return 0.00001, nil
+}
+
+func (b *backend) FeeHistogram(context.Context) ([][2]uint64, error) {
+	return [][2]uint64{{20, 1000}, {10, 5000}}, nil
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	b := newBackend(10)
+
+	server := electrumserver.NewServer(b, electrumserver.WithBanner("welcome"))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	client, err := electrum.NewClient(ctx, "tcp://"+listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	serverVer, protocolVer, err := client.ServerVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, electrumserver.ServerVersion, serverVer)
+	assert.Equal(t, electrum.ProtocolVersion, protocolVer)
+
+	banner, err := client.ServerBanner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "welcome", banner)
+
+	features, err := client.ServerFeatures(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, b.headers[0].BlockHash().String(), features.GenesisHash)
+
+	require.NoError(t, client.Ping(ctx))
+
+	header, err := client.GetBlockHeader(ctx, 3, 9)
+	require.NoError(t, err)
+	assert.NotEmpty(t, header.Branch)
+
+	headers, err := client.GetBlockHeaders(ctx, 0, 20, 9)
+	assert.ErrorIs(t, err, electrum.ErrCheckpointHeight)
+	headers, err = client.GetBlockHeaders(ctx, 2, 5, 9)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5), headers.Count)
+
+	balance, err := client.GetBalance(ctx, testScripthash)
+	require.NoError(t, err)
+	assert.Equal(t, btcutil.Amount(1000), balance.Confirmed)
+
+	_, err = client.GetBalance(ctx, "xyz")
+	assert.ErrorIs(t, err, electrum.ErrBadRequest)
+
+	_, err = client.GetRawTransaction(ctx, strings.Repeat("ab", chainhash.HashSize))
+	assert.ErrorIs(t, err, electrum.ErrNotFound)
+
+	_, err = client.BroadcastTransaction(ctx, "0100")
+	var rejected *electrum.TxRejectedError
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, "min relay fee not met", rejected.Reason)
+
+	histogram, err := client.GetFeeHistogram(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint32]uint64{20: 1000, 10: 5000}, histogram)
+
+	batch := client.Batch()
+	history := batch.GetHistory(testScripthash)
+	unspent := batch.ListUnspent(testScripthash)
+	require.NoError(t, batch.Send(ctx))
+	_, err = history.Result()
+	require.NoError(t, err)
+	_, err = unspent.Result()
+	require.NoError(t, err)
+}
+
+func TestServerNotifications(t *testing.T) {
+	ctx := context.Background()
+	b := newBackend(3)
+
+	server := electrumserver.NewServer(b)
+	defer server.Close()
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client, err := electrum.NewClient(ctx, "ws"+strings.TrimPrefix(httpServer.URL, "http"))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	tips, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), (<-tips).Height)
+
+	sub, notifs := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, testScripthash))
+
+	server.NotifyHeader(b.mine())
+	select {
+	case tip := <-tips:
+		assert.Equal(t, int32(3), tip.Height)
+	case <-time.After(2 * time.Second):
+		t.Fatal("header notification not received")
+	}
+
+	b.lock.Lock()
+	b.history = append(b.history, &electrum.GetMempoolResult{Hash: strings.Repeat("ab", chainhash.HashSize)})
+	b.lock.Unlock()
+
+	require.NoError(t, server.NotifyScripthash(ctx, testScripthash, "00"))
+	select {
+	case notif := <-notifs:
+		assert.Equal(t, testScripthash, notif.Params[0])
+		assert.Len(t, notif.Params[1], 64)
+	case <-time.After(2 * time.Second):
+		t.Fatal("scripthash notification not received")
+	}
+
+	// The status did not change, no notification is sent.
+	require.NoError(t, server.NotifyScripthash(ctx, testScripthash))
+	select {
+	case notif := <-notifs:
+		t.Fatalf("unexpected notification %v", notif)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServerSlowSession(t *testing.T) {
+	b := newBackend(3)
+
+	server := electrumserver.NewServer(b)
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"blockchain.headers.subscribe","params":[]}` + "\n"))
+	require.NoError(t, err)
+	_, err = bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+
+	// The session stops reading, its notifications pile up without blocking the server
+	// until it is disconnected.
+	height, header := b.mine()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100000; i++ {
+			server.NotifyHeader(height, header)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("notifications blocked by a slow session")
+	}
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	_, err = io.Copy(io.Discard, conn)
+	assert.NoError(t, err)
+}
+
+func TestServerMaxSubscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	server := electrumserver.NewServer(newBackend(3), electrumserver.WithMaxSubscriptions(1))
+	defer server.Close()
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client, err := electrum.NewClient(ctx, "ws"+strings.TrimPrefix(httpServer.URL, "http"))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	sub, _ := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, testScripthash))
+	// Subscribing again to the same scripthash does not count.
+	require.NoError(t, sub.Add(ctx, testScripthash))
+
+	err = sub.Add(ctx, strings.Repeat("00", chainhash.HashSize))
+	assert.ErrorIs(t, err, electrum.ErrBadRequest)
+}
@@ -0,0 +1,960 @@
+package electrumtest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/checksum0/go-electrum/electrum"
+)
+
+// headersChunkMax is the maximum number of headers returned by blockchain.block.headers.
+const headersChunkMax = 2016
+
+var (
+	// ErrTxNotFound throws an error if a transaction is neither in the mempool nor in the chain.
+	ErrTxNotFound = errors.New("electrumtest: transaction not found")
+
+	// ErrDoubleSpend throws an error if a transaction spends an output already spent in the chain.
+	ErrDoubleSpend = errors.New("electrumtest: output already spent")
+
+	// ErrReorgDepth throws an error if a reorganization would disconnect the genesis block.
+	ErrReorgDepth = errors.New("electrumtest: reorganization deeper than the chain")
+)
+
+// chainTx is a transaction of the mempool or of a block of the chain.
+type chainTx struct {
+	tx   *wire.MsgTx
+	hash chainhash.Hash
+	raw  []byte
+	fee  int64
+
+	// block is nil while the transaction is in the mempool.
+	block *chainBlock
+	pos   int
+
+	// scripthashes lists the scripthashes the transaction pays to or spends from.
+	scripthashes map[string]struct{}
+}
+
+type chainBlock struct {
+	header wire.BlockHeader
+	hash   chainhash.Hash
+	height int32
+	txs    []*chainTx
+}
+
+// Chain is a simulated regtest chain backing a Server: it answers the blockchain.* calls from
+// its blocks and mempool, and notifies the header and scripthash subscriptions when blocks are
+// mined, transactions are added or removed, and the chain is reorganized.
+//
+// Scripthash subscriptions are shared by every client of the server, each status change
+// being pushed to every connected client.
+type Chain struct {
+	server *Server
+	params *chaincfg.Params
+
+	blocks  []*chainBlock
+	mempool []*chainTx
+	txs     map[chainhash.Hash]*chainTx
+	spends  map[wire.OutPoint]*chainTx
+
+	// subscribed maps the subscribed scripthashes to their last notified status.
+	subscribed map[string]string
+	tip        chainhash.Hash
+	nonce      int64
+
+	lock sync.Mutex
+}
+
+// NewChain returns a chain holding the regtest genesis block, and installs its handlers on
+// server, replacing the previous handlers of the same methods.
+func NewChain(server *Server) *Chain {
+	params := &chaincfg.RegressionNetParams
+	genesis := &chainBlock{
+		header: params.GenesisBlock.Header,
+		hash:   *params.GenesisHash,
+	}
+
+	c := &Chain{
+		server:     server,
+		params:     params,
+		blocks:     []*chainBlock{genesis},
+		txs:        make(map[chainhash.Hash]*chainTx),
+		spends:     make(map[wire.OutPoint]*chainTx),
+		subscribed: make(map[string]string),
+		tip:        genesis.hash,
+	}
+
+	server.Handle("blockchain.headers.subscribe", c.handleHeadersSubscribe)
+	server.Handle("blockchain.block.header", c.handleBlockHeader)
+	server.Handle("blockchain.block.headers", c.handleBlockHeaders)
+	server.Handle("blockchain.scripthash.get_balance", c.handleGetBalance)
+	server.Handle("blockchain.scripthash.get_history", c.handleGetHistory)
+	server.Handle("blockchain.scripthash.get_mempool", c.handleGetMempool)
+	server.Handle("blockchain.scripthash.listunspent", c.handleListUnspent)
+	server.Handle("blockchain.scripthash.subscribe", c.handleSubscribe)
+	server.Handle("blockchain.scripthash.unsubscribe", c.handleUnsubscribe)
+	server.Handle("blockchain.transaction.broadcast", c.handleBroadcast)
+	server.Handle("blockchain.transaction.get", c.handleGetTransaction)
+	server.Handle("blockchain.transaction.get_merkle", c.handleGetMerkle)
+	server.Handle("blockchain.transaction.id_from_pos", c.handleIDFromPos)
+
+	return c
+}
+
+// Params returns the parameters of the simulated network.
+func (c *Chain) Params() *chaincfg.Params {
+	return c.params
+}
+
+// Height returns the height of the chain tip.
+func (c *Chain) Height() int32 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.height()
+}
+
+func (c *Chain) height() int32 {
+	return int32(len(c.blocks) - 1)
+}
+
+// Header returns the header of the block at height.
+func (c *Chain) Header(height int32) (*wire.BlockHeader, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if height < 0 || height > c.height() {
+		return nil, electrum.ErrHeaderNotFound
+	}
+
+	header := c.blocks[height].header
+
+	return &header, nil
+}
+
+// Mine mines n blocks on top of the chain, the first one confirming every transaction of the
+// mempool. The coinbase outputs pay to payTo, or to an anyone-can-spend script by default.
+// It returns the hashes of the new blocks.
+func (c *Chain) Mine(n int, payTo ...[]byte) []chainhash.Hash {
+	script := []byte{txscript.OP_TRUE}
+	if len(payTo) > 0 {
+		script = payTo[0]
+	}
+
+	c.lock.Lock()
+	hashes := make([]chainhash.Hash, 0, n)
+	for i := 0; i < n; i++ {
+		hashes = append(hashes, c.mine(script))
+	}
+	notify := c.changes()
+	c.lock.Unlock()
+
+	notify()
+
+	return hashes
+}
+
+func (c *Chain) mine(script []byte) chainhash.Hash {
+	prev := c.blocks[len(c.blocks)-1]
+	height := prev.height + 1
+
+	c.nonce++
+	sigScript, _ := txscript.NewScriptBuilder().AddInt64(int64(height)).AddInt64(c.nonce).Script()
+
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sigScript, nil))
+	coinbase.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(height, c.params), script))
+
+	block := &chainBlock{height: height}
+	block.txs = append([]*chainTx{c.newTx(coinbase)}, c.mempool...)
+	c.mempool = nil
+
+	leaves := make([]chainhash.Hash, len(block.txs))
+	for i, tx := range block.txs {
+		tx.block = block
+		tx.pos = i
+		leaves[i] = tx.hash
+	}
+	c.index(block.txs[0])
+
+	_, root := electrum.MerkleBranch(leaves, 0)
+	block.header = wire.BlockHeader{
+		Version:    4,
+		PrevBlock:  prev.hash,
+		MerkleRoot: root,
+		Timestamp:  prev.header.Timestamp.Add(10 * time.Minute),
+		Bits:       c.params.PowLimitBits,
+	}
+
+	target := blockchain.CompactToBig(block.header.Bits)
+	for {
+		block.hash = block.header.BlockHash()
+		if blockchain.HashToBig(&block.hash).Cmp(target) <= 0 {
+			break
+		}
+		block.header.Nonce++
+	}
+
+	c.blocks = append(c.blocks, block)
+
+	return block.hash
+}
+
+// Reorg disconnects the last depth blocks, moving their transactions back to the mempool.
+// The transactions spending the disconnected coinbases are removed. The chain is usually
+// extended again with Mine() to build the new branch.
+func (c *Chain) Reorg(depth int) error {
+	c.lock.Lock()
+	if depth >= len(c.blocks) {
+		c.lock.Unlock()
+		return ErrReorgDepth
+	}
+
+	disconnected := c.blocks[len(c.blocks)-depth:]
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+
+	var mempool []*chainTx
+	for _, block := range disconnected {
+		for _, tx := range block.txs {
+			tx.block = nil
+			mempool = append(mempool, tx)
+		}
+	}
+	c.mempool = append(mempool, c.mempool...)
+
+	for _, block := range disconnected {
+		c.remove(block.txs[0])
+	}
+	notify := c.changes()
+	c.lock.Unlock()
+
+	notify()
+
+	return nil
+}
+
+// AddTransaction adds tx to the mempool, replacing the mempool transactions spending the same
+// outputs. Spending unknown outputs is allowed, the fee of tx is then unknown and set to 0.
+func (c *Chain) AddTransaction(tx *wire.MsgTx) error {
+	c.lock.Lock()
+	err := c.add(tx)
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	notify := c.changes()
+	c.lock.Unlock()
+
+	notify()
+
+	return nil
+}
+
+func (c *Chain) add(msg *wire.MsgTx) error {
+	tx := c.newTx(msg)
+	if _, ok := c.txs[tx.hash]; ok {
+		return nil
+	}
+
+	for _, in := range msg.TxIn {
+		if spender, ok := c.spends[in.PreviousOutPoint]; ok && spender.block != nil {
+			return fmt.Errorf("%w: %v", ErrDoubleSpend, in.PreviousOutPoint)
+		}
+	}
+	for _, in := range msg.TxIn {
+		if spender, ok := c.spends[in.PreviousOutPoint]; ok {
+			c.remove(spender)
+		}
+	}
+
+	c.mempool = append(c.mempool, tx)
+	c.index(tx)
+
+	return nil
+}
+
+// Fund adds to the mempool a transaction paying amount to script, spending an output unknown
+// to the chain, and returns it.
+func (c *Chain) Fund(script []byte, amount btcutil.Amount) (*wire.MsgTx, error) {
+	c.lock.Lock()
+	c.nonce++
+	prev := chainhash.DoubleHashH([]byte(fmt.Sprintf("electrumtest fund %d", c.nonce)))
+	c.lock.Unlock()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prev, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(int64(amount), script))
+
+	err := c.AddTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Drop removes the transaction txid from the mempool, with the transactions spending it.
+func (c *Chain) Drop(txid string) error {
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	tx, ok := c.txs[*hash]
+	if !ok || tx.block != nil {
+		c.lock.Unlock()
+		return fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+	}
+
+	c.remove(tx)
+	notify := c.changes()
+	c.lock.Unlock()
+
+	notify()
+
+	return nil
+}
+
+func (c *Chain) newTx(msg *wire.MsgTx) *chainTx {
+	var buf bytes.Buffer
+	_ = msg.Serialize(&buf)
+
+	return &chainTx{
+		tx:   msg,
+		hash: msg.TxHash(),
+		raw:  buf.Bytes(),
+	}
+}
+
+// index records the outputs spent by tx, and the scripthashes it touches.
+func (c *Chain) index(tx *chainTx) {
+	tx.scripthashes = make(map[string]struct{})
+	for _, out := range tx.tx.TxOut {
+		tx.scripthashes[electrum.ScriptToElectrumScriptHash(out.PkScript)] = struct{}{}
+	}
+
+	if blockchain.IsCoinBaseTx(tx.tx) {
+		c.txs[tx.hash] = tx
+		return
+	}
+
+	var in, out int64
+	known := true
+	for _, txIn := range tx.tx.TxIn {
+		c.spends[txIn.PreviousOutPoint] = tx
+
+		prevOut := c.output(txIn.PreviousOutPoint)
+		if prevOut == nil {
+			known = false
+			continue
+		}
+
+		in += prevOut.Value
+		tx.scripthashes[electrum.ScriptToElectrumScriptHash(prevOut.PkScript)] = struct{}{}
+	}
+	for _, txOut := range tx.tx.TxOut {
+		out += txOut.Value
+	}
+	if known && in > out {
+		tx.fee = in - out
+	}
+
+	c.txs[tx.hash] = tx
+}
+
+// remove deletes tx from the mempool and the index, with the mempool transactions spending it.
+func (c *Chain) remove(tx *chainTx) {
+	if _, ok := c.txs[tx.hash]; !ok {
+		return
+	}
+
+	delete(c.txs, tx.hash)
+	for _, in := range tx.tx.TxIn {
+		if c.spends[in.PreviousOutPoint] == tx {
+			delete(c.spends, in.PreviousOutPoint)
+		}
+	}
+	for i, mempoolTx := range c.mempool {
+		if mempoolTx == tx {
+			c.mempool = append(c.mempool[:i:i], c.mempool[i+1:]...)
+			break
+		}
+	}
+
+	for i := range tx.tx.TxOut {
+		if spender, ok := c.spends[wire.OutPoint{Hash: tx.hash, Index: uint32(i)}]; ok {
+			c.remove(spender)
+		}
+	}
+}
+
+func (c *Chain) output(outpoint wire.OutPoint) *wire.TxOut {
+	tx, ok := c.txs[outpoint.Hash]
+	if !ok || int(outpoint.Index) >= len(tx.tx.TxOut) {
+		return nil
+	}
+
+	return tx.tx.TxOut[outpoint.Index]
+}
+
+// mempoolHeight returns -1 if tx spends an unconfirmed output, 0 otherwise.
+func (c *Chain) mempoolHeight(tx *chainTx) int32 {
+	for _, in := range tx.tx.TxIn {
+		if parent, ok := c.txs[in.PreviousOutPoint.Hash]; ok && parent.block == nil {
+			return -1
+		}
+	}
+
+	return 0
+}
+
+// history returns the transactions touching scripthash, the confirmed ones in chain order
+// followed by the mempool ones.
+func (c *Chain) history(scripthash string) []*electrum.GetMempoolResult {
+	var history []*electrum.GetMempoolResult
+	for _, block := range c.blocks {
+		for _, tx := range block.txs {
+			if _, ok := tx.scripthashes[scripthash]; ok {
+				history = append(history, &electrum.GetMempoolResult{Hash: tx.hash.String(), Height: block.height})
+			}
+		}
+	}
+
+	return append(history, c.mempoolHistory(scripthash)...)
+}
+
+// mempoolHistory returns the mempool transactions touching scripthash, in the order
+// of electrum.SortHistory().
+func (c *Chain) mempoolHistory(scripthash string) []*electrum.GetMempoolResult {
+	var history []*electrum.GetMempoolResult
+	for _, tx := range c.mempool {
+		if _, ok := tx.scripthashes[scripthash]; ok {
+			history = append(history, &electrum.GetMempoolResult{
+				Hash:   tx.hash.String(),
+				Height: c.mempoolHeight(tx),
+				Fee:    uint32(tx.fee),
+			})
+		}
+	}
+
+	electrum.SortHistory(history)
+
+	return history
+}
+
+// status returns the status hash of scripthash, or an empty string without history.
+func (c *Chain) status(scripthash string) string {
+	return electrum.ScripthashStatus(c.history(scripthash))
+}
+
+// unspent returns the outputs paying to scripthash not spent in the chain or the mempool.
+func (c *Chain) unspent(scripthash string) []*electrum.ListUnspentResult {
+	var unspent []*electrum.ListUnspentResult
+	add := func(tx *chainTx, height int32) {
+		if _, ok := tx.scripthashes[scripthash]; !ok {
+			return
+		}
+
+		for i, out := range tx.tx.TxOut {
+			if electrum.ScriptToElectrumScriptHash(out.PkScript) != scripthash {
+				continue
+			}
+			if _, ok := c.spends[wire.OutPoint{Hash: tx.hash, Index: uint32(i)}]; ok {
+				continue
+			}
+
+			unspent = append(unspent, &electrum.ListUnspentResult{
+				Height:   uint32(height),
+				Position: uint32(i),
+				Hash:     tx.hash.String(),
+				Value:    uint64(out.Value),
+			})
+		}
+	}
+
+	for _, block := range c.blocks {
+		for _, tx := range block.txs {
+			add(tx, block.height)
+		}
+	}
+	for _, tx := range c.mempool {
+		add(tx, 0)
+	}
+
+	return unspent
+}
+
+// balance returns the value of the confirmed unspent outputs paying to scripthash, and the
+// value received minus the value spent by the mempool transactions.
+func (c *Chain) balance(scripthash string) electrum.GetBalanceResult {
+	var result electrum.GetBalanceResult
+	for _, tx := range c.txs {
+		if _, ok := tx.scripthashes[scripthash]; !ok {
+			continue
+		}
+
+		for i, out := range tx.tx.TxOut {
+			if electrum.ScriptToElectrumScriptHash(out.PkScript) != scripthash {
+				continue
+			}
+
+			value := btcutil.Amount(out.Value)
+			spender, spent := c.spends[wire.OutPoint{Hash: tx.hash, Index: uint32(i)}]
+			if tx.block == nil {
+				result.Unconfirmed += value
+			} else if !spent || spender.block == nil {
+				result.Confirmed += value
+			}
+			if spent && spender.block == nil {
+				result.Unconfirmed -= value
+			}
+		}
+	}
+
+	return result
+}
+
+// changes returns a function sending the notifications for the tip and the statuses that
+// changed since the last notifications, to be called once the lock is released.
+func (c *Chain) changes() func() {
+	var notifs [][]interface{}
+
+	tip := c.blocks[len(c.blocks)-1]
+	if tip.hash != c.tip {
+		c.tip = tip.hash
+		notifs = append(notifs, []interface{}{"blockchain.headers.subscribe", c.headerResult(tip)})
+	}
+
+	for scripthash, last := range c.subscribed {
+		status := c.status(scripthash)
+		if status == last {
+			continue
+		}
+
+		c.subscribed[scripthash] = status
+		notifs = append(notifs, []interface{}{"blockchain.scripthash.subscribe", scripthash, statusResult(status)})
+	}
+
+	return func() {
+		for _, notif := range notifs {
+			_ = c.server.Notify(notif[0].(string), notif[1:]...)
+		}
+	}
+}
+
+func (c *Chain) headerResult(block *chainBlock) *electrum.SubscribeHeadersResult {
+	return &electrum.SubscribeHeadersResult{
+		Height: block.height,
+		Hex:    headerHex(&block.header),
+	}
+}
+
+func headerHex(header *wire.BlockHeader) string {
+	var buf bytes.Buffer
+	_ = header.Serialize(&buf)
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// statusResult returns the JSON value of a status, null without history.
+func statusResult(status string) interface{} {
+	if status == "" {
+		return nil
+	}
+
+	return status
+}
+
+// unmarshalParams decodes the params of a call into v, the missing trailing params being
+// left untouched.
+func unmarshalParams(params []json.RawMessage, v ...interface{}) error {
+	for i, param := range params {
+		if i >= len(v) {
+			break
+		}
+
+		err := json.Unmarshal(param, v[i])
+		if err != nil {
+			return &electrum.RPCError{Code: electrum.CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+func (c *Chain) handleHeadersSubscribe(params []json.RawMessage) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.headerResult(c.blocks[len(c.blocks)-1]), nil
+}
+
+// checkpointProof returns the merkle root of the block hashes up to cpHeight, and the branch
+// proving the block hash at height.
+func (c *Chain) checkpointProof(height, cpHeight uint32) ([]string, string, error) {
+	if height > cpHeight || int32(cpHeight) > c.height() {
+		return nil, "", &electrum.RPCError{
+			Code:    electrum.CodeBadRequest,
+			Message: fmt.Sprintf("header height %d must be <= cp_height %d which must be <= chain height %d", height, cpHeight, c.height()),
+		}
+	}
+
+	leaves := make([]chainhash.Hash, cpHeight+1)
+	for i := range leaves {
+		leaves[i] = c.blocks[i].hash
+	}
+	branch, root := electrum.MerkleBranch(leaves, height)
+
+	return branch, root.String(), nil
+}
+
+func (c *Chain) handleBlockHeader(params []json.RawMessage) (interface{}, error) {
+	var height, cpHeight uint32
+	if err := unmarshalParams(params, &height, &cpHeight); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if int32(height) > c.height() {
+		return nil, &electrum.RPCError{Code: electrum.CodeBadRequest, Message: fmt.Sprintf("height %d out of range", height)}
+	}
+
+	header := headerHex(&c.blocks[height].header)
+	if cpHeight == 0 {
+		return header, nil
+	}
+
+	branch, root, err := c.checkpointProof(height, cpHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &electrum.GetBlockHeaderResult{Branch: branch, Header: header, Root: root}, nil
+}
+
+func (c *Chain) handleBlockHeaders(params []json.RawMessage) (interface{}, error) {
+	var start, count, cpHeight uint32
+	if err := unmarshalParams(params, &start, &count, &cpHeight); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if count > headersChunkMax {
+		count = headersChunkMax
+	}
+
+	var headers strings.Builder
+	var n uint32
+	for height := start; n < count && int32(height) <= c.height(); height++ {
+		headers.WriteString(headerHex(&c.blocks[height].header))
+		n++
+	}
+
+	result := &electrum.GetBlockHeadersResult{Count: n, Headers: headers.String(), Max: headersChunkMax}
+	if cpHeight != 0 && n > 0 {
+		branch, root, err := c.checkpointProof(start+n-1, cpHeight)
+		if err != nil {
+			return nil, err
+		}
+		result.Branch, result.Root = branch, root
+	}
+
+	return result, nil
+}
+
+func (c *Chain) handleGetBalance(params []json.RawMessage) (interface{}, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.balance(scripthash), nil
+}
+
+func (c *Chain) handleGetHistory(params []json.RawMessage) (interface{}, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return nonNil(c.history(scripthash)), nil
+}
+
+func (c *Chain) handleGetMempool(params []json.RawMessage) (interface{}, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return nonNil(c.mempoolHistory(scripthash)), nil
+}
+
+func (c *Chain) handleListUnspent(params []json.RawMessage) (interface{}, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	unspent := c.unspent(scripthash)
+	if unspent == nil {
+		unspent = []*electrum.ListUnspentResult{}
+	}
+
+	return unspent, nil
+}
+
+// nonNil returns an empty history instead of nil, so it is sent as an empty array.
+func nonNil(history []*electrum.GetMempoolResult) []*electrum.GetMempoolResult {
+	if history == nil {
+		return []*electrum.GetMempoolResult{}
+	}
+
+	return history
+}
+
+func (c *Chain) handleSubscribe(params []json.RawMessage) (interface{}, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	status := c.status(scripthash)
+	c.subscribed[scripthash] = status
+
+	return statusResult(status), nil
+}
+
+func (c *Chain) handleUnsubscribe(params []json.RawMessage) (interface{}, error) {
+	var scripthash string
+	if err := unmarshalParams(params, &scripthash); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, ok := c.subscribed[scripthash]
+	delete(c.subscribed, scripthash)
+
+	return ok, nil
+}
+
+func (c *Chain) handleBroadcast(params []json.RawMessage) (interface{}, error) {
+	var rawTx string
+	if err := unmarshalParams(params, &rawTx); err != nil {
+		return nil, err
+	}
+
+	raw, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, rejected("TX decode failed", rawTx)
+	}
+
+	tx := &wire.MsgTx{}
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, rejected("TX decode failed", rawTx)
+	}
+
+	err = c.AddTransaction(tx)
+	if err != nil {
+		return nil, rejected("bad-txns-inputs-missingorspent", rawTx)
+	}
+
+	return tx.TxHash().String(), nil
+}
+
+// rejected returns the error sent by ElectrumX when the node rejects a broadcast transaction.
+func rejected(reason, rawTx string) error {
+	return &electrum.RPCError{
+		Code:    electrum.CodeBadRequest,
+		Message: fmt.Sprintf("the transaction was rejected by network rules.\n\n%s\n[%s]", reason, rawTx),
+	}
+}
+
+// lookup returns the transaction txid, or an error matching electrum.ErrNotFound.
+func (c *Chain) lookup(txid string) (*chainTx, error) {
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err == nil {
+		if tx, ok := c.txs[*hash]; ok {
+			return tx, nil
+		}
+	}
+
+	return nil, &electrum.RPCError{
+		Code:    electrum.CodeDaemonError,
+		Message: "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.",
+	}
+}
+
+func (c *Chain) handleGetTransaction(params []json.RawMessage) (interface{}, error) {
+	var txid string
+	var verbose bool
+	if err := unmarshalParams(params, &txid, &verbose); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tx, err := c.lookup(txid)
+	if err != nil {
+		return nil, err
+	}
+	if !verbose {
+		return hex.EncodeToString(tx.raw), nil
+	}
+
+	return c.verboseTx(tx), nil
+}
+
+// verboseTx returns tx in the format of bitcoind getrawtransaction.
+func (c *Chain) verboseTx(tx *chainTx) *electrum.GetTransactionResult {
+	result := &electrum.GetTransactionResult{
+		Hash:     tx.hash.String(),
+		Hex:      hex.EncodeToString(tx.raw),
+		Locktime: tx.tx.LockTime,
+		Size:     uint32(len(tx.raw)),
+		Version:  uint32(tx.tx.Version),
+	}
+
+	if tx.block != nil {
+		result.Blockhash = tx.block.hash.String()
+		result.Blocktime = uint64(tx.block.header.Timestamp.Unix())
+		result.Time = result.Blocktime
+		result.Confirmations = c.height() - tx.block.height + 1
+	}
+
+	for _, in := range tx.tx.TxIn {
+		vin := electrum.Vin{Sequence: in.Sequence}
+		if blockchain.IsCoinBaseTx(tx.tx) {
+			vin.Coinbase = hex.EncodeToString(in.SignatureScript)
+		} else {
+			vin.TxID = in.PreviousOutPoint.Hash.String()
+			vin.Vout = in.PreviousOutPoint.Index
+			vin.ScriptSig = &electrum.ScriptSig{Hex: hex.EncodeToString(in.SignatureScript)}
+		}
+		result.Vin = append(result.Vin, vin)
+	}
+
+	for i, out := range tx.tx.TxOut {
+		class, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(out.PkScript, c.params)
+		asm, _ := txscript.DisasmString(out.PkScript)
+
+		vout := electrum.Vout{
+			N:     uint32(i),
+			Value: btcutil.Amount(out.Value),
+			ScriptPubkey: electrum.ScriptPubkey{
+				Asm:     asm,
+				Hex:     hex.EncodeToString(out.PkScript),
+				ReqSigs: uint32(reqSigs),
+				Type:    class.String(),
+			},
+		}
+		for _, addr := range addrs {
+			vout.ScriptPubkey.Addresses = append(vout.ScriptPubkey.Addresses, addr.EncodeAddress())
+		}
+		result.Vout = append(result.Vout, vout)
+	}
+
+	return result
+}
+
+// blockBranch returns the merkle branch of the transaction at pos in block.
+func blockBranch(block *chainBlock, pos int) []string {
+	leaves := make([]chainhash.Hash, len(block.txs))
+	for i, tx := range block.txs {
+		leaves[i] = tx.hash
+	}
+	branch, _ := electrum.MerkleBranch(leaves, uint32(pos))
+
+	return branch
+}
+
+func (c *Chain) handleGetMerkle(params []json.RawMessage) (interface{}, error) {
+	var txid string
+	var height uint32
+	if err := unmarshalParams(params, &txid, &height); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tx, err := c.lookup(txid)
+	if err != nil {
+		return nil, err
+	}
+	if tx.block == nil || uint32(tx.block.height) != height {
+		return nil, &electrum.RPCError{
+			Code:    electrum.CodeBadRequest,
+			Message: fmt.Sprintf("tx %s not in block at height %d", txid, height),
+		}
+	}
+
+	return &electrum.GetMerkleProofResult{
+		Merkle:   blockBranch(tx.block, tx.pos),
+		Height:   height,
+		Position: uint32(tx.pos),
+	}, nil
+}
+
+func (c *Chain) handleIDFromPos(params []json.RawMessage) (interface{}, error) {
+	var height, pos uint32
+	var merkle bool
+	if err := unmarshalParams(params, &height, &pos, &merkle); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if int32(height) > c.height() || int(pos) >= len(c.blocks[height].txs) {
+		return nil, &electrum.RPCError{
+			Code:    electrum.CodeBadRequest,
+			Message: fmt.Sprintf("no tx at position %d in block at height %d", pos, height),
+		}
+	}
+
+	block := c.blocks[height]
+	hash := block.txs[pos].hash.String()
+	if !merkle {
+		return hash, nil
+	}
+
+	return &electrum.GetMerkleProofFromPosResult{Hash: hash, Merkle: blockBranch(block, int(pos))}, nil
+}
@@ -0,0 +1,119 @@
+package electrumtest_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, c <-chan *electrum.SubscribeNotif) *electrum.SubscribeNotif {
+	t.Helper()
+
+	select {
+	case notif := <-c:
+		return notif
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification not received")
+		return nil
+	}
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(ctx)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	headers, err := client.SubscribeHeaders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), (<-headers).Height)
+
+	chain.Mine(1)
+	select {
+	case header := <-headers:
+		assert.Equal(t, int32(1), header.Height)
+	case <-time.After(2 * time.Second):
+		t.Fatal("header notification not received")
+	}
+
+	script := []byte{txscript.OP_1, txscript.OP_DROP, txscript.OP_TRUE}
+	scripthash := electrum.ScriptToElectrumScriptHash(script)
+
+	sub, notifs := client.SubscribeScripthash()
+	require.NoError(t, sub.Add(ctx, scripthash))
+
+	funding, err := chain.Fund(script, btcutil.SatoshiPerBitcoin)
+	require.NoError(t, err)
+	txid := funding.TxHash().String()
+
+	sum := sha256.Sum256([]byte(txid + ":0:"))
+	assert.Equal(t, [2]string{scripthash, hex.EncodeToString(sum[:])}, receive(t, notifs).Params)
+
+	balance, err := client.GetBalance(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Unconfirmed: btcutil.SatoshiPerBitcoin}, balance)
+
+	chain.Mine(1)
+	receive(t, notifs)
+
+	history, err := client.GetHistory(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: txid, Height: 2}}, history)
+
+	unspent, err := client.ListUnspent(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.ListUnspentResult{{Height: 2, Hash: txid, Value: btcutil.SatoshiPerBitcoin}}, unspent)
+
+	verified, err := client.GetVerifiedTransaction(ctx, txid, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), verified.Position)
+
+	_, err = client.GetBlockHeader(ctx, 1, 2)
+	require.NoError(t, err)
+	_, err = client.GetBlockHeaders(ctx, 0, 3, 2)
+	require.NoError(t, err)
+
+	fundingHash := funding.TxHash()
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundingHash, 0), nil, nil))
+	spend.AddTxOut(wire.NewTxOut(btcutil.SatoshiPerBitcoin-1000, []byte{txscript.OP_TRUE}))
+	require.NoError(t, chain.AddTransaction(spend))
+	receive(t, notifs)
+
+	mempool, err := client.GetMempool(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: spend.TxHash().String(), Height: 0, Fee: 1000}}, mempool)
+
+	balance, err = client.GetBalance(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: btcutil.SatoshiPerBitcoin, Unconfirmed: -btcutil.SatoshiPerBitcoin}, balance)
+
+	require.NoError(t, chain.Drop(spend.TxHash().String()))
+	receive(t, notifs)
+
+	require.NoError(t, chain.Reorg(1))
+	receive(t, notifs)
+	assert.Equal(t, int32(1), chain.Height())
+
+	history, err = client.GetHistory(ctx, scripthash)
+	require.NoError(t, err)
+	assert.Equal(t, []*electrum.GetMempoolResult{{Hash: txid, Height: 0}}, history)
+
+	_, err = client.GetMerkleProof(ctx, txid, 2)
+	assert.ErrorIs(t, err, electrum.ErrBadRequest)
+}
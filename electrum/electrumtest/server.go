@@ -0,0 +1,468 @@
+// Package electrumtest provides a scriptable Electrum server to test code using an
+// electrum.Client offline, either in memory or listening on a loopback port.
+package electrumtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/checksum0/go-electrum/electrum"
+)
+
+var (
+	// ErrServerClosed throws an error if a closed server is dialed.
+	ErrServerClosed = errors.New("electrumtest: server closed")
+
+	// ErrNoHandler is returned to the client, as a method not found error, for the calls
+	// without handler.
+	ErrNoHandler = errors.New("electrumtest: no handler for method")
+)
+
+// Handler answers a call with its result, or with an error. Errors of type *electrum.RPCError
+// are sent as is, other errors are sent with the electrum.CodeBadRequest code.
+type Handler func(params []json.RawMessage) (interface{}, error)
+
+// Result returns a Handler always answering with result.
+func Result(result interface{}) Handler {
+	return func([]json.RawMessage) (interface{}, error) {
+		return result, nil
+	}
+}
+
+// Error returns a Handler always answering with an error of the given code and message.
+func Error(code int, message string) Handler {
+	return func([]json.RawMessage) (interface{}, error) {
+		return nil, &electrum.RPCError{Code: code, Message: message}
+	}
+}
+
+// Request is a call received by the server.
+type Request struct {
+	ID     uint64            `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string             `json:"jsonrpc"`
+	ID      uint64             `json:"id"`
+	Result  interface{}        `json:"result"`
+	Error   *electrum.RPCError `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// session is a connection of a client to the server.
+type session interface {
+	send(line []byte) error
+	close()
+}
+
+// Server is a scriptable Electrum server. It answers server.version and server.ping by
+// default, every other method needs a handler.
+type Server struct {
+	handlers map[string]Handler
+	latency  time.Duration
+	requests []*Request
+
+	sessions  map[session]struct{}
+	listeners []net.Listener
+	closed    bool
+
+	lock sync.Mutex
+}
+
+// NewServer returns a server negotiating protocol version 1.4.
+func NewServer() *Server {
+	s := &Server{
+		handlers: make(map[string]Handler),
+		sessions: make(map[session]struct{}),
+	}
+
+	s.Handle("server.version", Result([]string{"electrumtest 1.0", "1.4"}))
+	s.Handle("server.ping", Result(nil))
+
+	return s
+}
+
+// Handle sets the handler of method, replacing the previous one.
+func (s *Server) Handle(method string, handler Handler) {
+	s.lock.Lock()
+	s.handlers[method] = handler
+	s.lock.Unlock()
+}
+
+// SetResult answers every call of method with result.
+func (s *Server) SetResult(method string, result interface{}) {
+	s.Handle(method, Result(result))
+}
+
+// SetError answers every call of method with an error of the given code and message.
+func (s *Server) SetError(method string, code int, message string) {
+	s.Handle(method, Error(code, message))
+}
+
+// SetLatency delays every response by latency.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.lock.Lock()
+	s.latency = latency
+	s.lock.Unlock()
+}
+
+// Requests returns the calls received so far, in order.
+func (s *Server) Requests() []*Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]*Request(nil), s.requests...)
+}
+
+// Calls returns the number of calls of method received so far.
+func (s *Server) Calls(method string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := 0
+	for _, req := range s.requests {
+		if req.Method == method {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Notify pushes a notification of method with params to every connected client.
+func (s *Server) Notify(method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	line, err := json.Marshal(&notification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	s.broadcast(line)
+
+	return nil
+}
+
+// SendRaw writes line, typically a malformed message, to every connected client.
+func (s *Server) SendRaw(line string) {
+	s.broadcast([]byte(line))
+}
+
+func (s *Server) broadcast(line []byte) {
+	for _, sess := range s.connected() {
+		_ = sess.send(line)
+	}
+}
+
+func (s *Server) connected() []session {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sessions := make([]session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+
+	return sessions
+}
+
+// Disconnect drops every connected client. The server keeps accepting new connections.
+func (s *Server) Disconnect() {
+	for _, sess := range s.connected() {
+		sess.close()
+	}
+}
+
+// Close drops every connected client and stops accepting new connections.
+func (s *Server) Close() {
+	s.lock.Lock()
+	s.closed = true
+	listeners := s.listeners
+	s.listeners = nil
+	s.lock.Unlock()
+
+	for _, listener := range listeners {
+		_ = listener.Close()
+	}
+
+	s.Disconnect()
+}
+
+func (s *Server) addSession(sess session) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.sessions[sess] = struct{}{}
+
+	return true
+}
+
+func (s *Server) removeSession(sess session) {
+	s.lock.Lock()
+	delete(s.sessions, sess)
+	s.lock.Unlock()
+}
+
+// handle answers a received line, holding a single call or a batch of calls.
+func (s *Server) handle(sess session, line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	if line[0] == '[' {
+		var reqs []*Request
+		if json.Unmarshal(line, &reqs) != nil {
+			return
+		}
+
+		resps := make([]*response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = s.answer(req)
+		}
+		s.reply(sess, resps)
+
+		return
+	}
+
+	req := &Request{}
+	if json.Unmarshal(line, req) != nil {
+		return
+	}
+
+	s.reply(sess, s.answer(req))
+}
+
+func (s *Server) answer(req *Request) *response {
+	s.lock.Lock()
+	s.requests = append(s.requests, req)
+	handler, ok := s.handlers[req.Method]
+	s.lock.Unlock()
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if !ok {
+		resp.Error = &electrum.RPCError{
+			Code:    electrum.CodeMethodNotFound,
+			Message: fmt.Sprintf("%v: %s", ErrNoHandler, req.Method),
+		}
+		return resp
+	}
+
+	result, err := handler(req.Params)
+	if err != nil {
+		var rpcErr *electrum.RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &electrum.RPCError{Code: electrum.CodeBadRequest, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+
+	resp.Result = result
+
+	return resp
+}
+
+func (s *Server) reply(sess session, v interface{}) {
+	s.lock.Lock()
+	latency := s.latency
+	s.lock.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	line, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	_ = sess.send(line)
+}
+
+// Transport returns a new in-memory connection to the server.
+func (s *Server) Transport() (electrum.Transport, error) {
+	t := &memTransport{
+		server:    s,
+		requests:  make(chan []byte, 16),
+		responses: make(chan []byte),
+		errors:    make(chan error, 1),
+		closed:    make(chan struct{}),
+	}
+	if !s.addSession(t) {
+		return nil, ErrServerClosed
+	}
+
+	go t.serve()
+
+	return t, nil
+}
+
+// Dialer returns a TransportDialer opening in-memory connections to the server, to be used
+// with electrum.NewReconnectingClient() or electrum.WithTransportDialer().
+func (s *Server) Dialer() electrum.TransportDialer {
+	return func(ctx context.Context) (electrum.Transport, error) {
+		return s.Transport()
+	}
+}
+
+// NewClient returns a client connected in memory to the server, configured by opts.
+func (s *Server) NewClient(ctx context.Context, opts ...electrum.Option) (*electrum.Client, error) {
+	return electrum.NewClient(ctx, "", append(opts, electrum.WithTransportDialer(s.Dialer()))...)
+}
+
+// Listen accepts TCP connections on a loopback port, and returns its address.
+func (s *Server) Listen() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		_ = listener.Close()
+		return "", ErrServerClosed
+	}
+	s.listeners = append(s.listeners, listener)
+	s.lock.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			sess := &tcpSession{conn: conn}
+			if !s.addSession(sess) {
+				_ = conn.Close()
+				return
+			}
+
+			go sess.serve(s)
+		}
+	}()
+
+	return listener.Addr().String(), nil
+}
+
+// memTransport is an in-memory electrum.Transport connected to a Server.
+type memTransport struct {
+	server    *Server
+	requests  chan []byte
+	responses chan []byte
+	errors    chan error
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (t *memTransport) serve() {
+	for {
+		select {
+		case <-t.closed:
+			return
+		case line := <-t.requests:
+			t.server.handle(t, line)
+		}
+	}
+}
+
+// SendMessage implements electrum.Transport.
+func (t *memTransport) SendMessage(body []byte) error {
+	select {
+	case <-t.closed:
+		return io.ErrClosedPipe
+	case t.requests <- append([]byte(nil), body...):
+		return nil
+	}
+}
+
+// Responses implements electrum.Transport.
+func (t *memTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// Errors implements electrum.Transport.
+func (t *memTransport) Errors() <-chan error {
+	return t.errors
+}
+
+// Close implements electrum.Transport.
+func (t *memTransport) Close() error {
+	t.close()
+
+	return nil
+}
+
+func (t *memTransport) send(line []byte) error {
+	select {
+	case <-t.closed:
+		return io.ErrClosedPipe
+	case t.responses <- line:
+		return nil
+	}
+}
+
+func (t *memTransport) close() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		t.errors <- io.EOF
+		t.server.removeSession(t)
+	})
+}
+
+// tcpSession is a TCP connection accepted by a Server.
+type tcpSession struct {
+	conn      net.Conn
+	writeLock sync.Mutex
+}
+
+func (c *tcpSession) serve(s *Server) {
+	defer s.removeSession(c)
+	defer c.conn.Close()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		s.handle(c, line)
+	}
+}
+
+func (c *tcpSession) send(line []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	_, err := c.conn.Write(append(line, '\n'))
+
+	return err
+}
+
+func (c *tcpSession) close() {
+	_ = c.conn.Close()
+}
@@ -0,0 +1,216 @@
+package electrum
+
+import (
+	"context"
+	"sync"
+)
+
+// ScripthashEventType is the kind of change reported by a ScripthashEvent.
+type ScripthashEventType int
+
+const (
+	// EventMempoolTx reports a new unconfirmed transaction.
+	EventMempoolTx ScripthashEventType = iota + 1
+
+	// EventTxConfirmed reports a transaction confirmed at Height, including a new transaction
+	// first seen confirmed and a transaction confirmed again in another block after a
+	// reorganization.
+	EventTxConfirmed
+
+	// EventTxDropped reports an unconfirmed transaction removed from the mempool, for
+	// instance evicted or replaced by a conflicting transaction.
+	EventTxDropped
+
+	// EventTxReorged reports a confirmed transaction disconnected by a reorganization, either
+	// moved back to the mempool, Height being then 0 or -1, or removed, Height being then its
+	// previous height.
+	EventTxReorged
+
+	// EventBalanceChanged reports a new balance of the scripthash.
+	EventBalanceChanged
+)
+
+func (t ScripthashEventType) String() string {
+	switch t {
+	case EventMempoolTx:
+		return "mempool_tx"
+	case EventTxConfirmed:
+		return "tx_confirmed"
+	case EventTxDropped:
+		return "tx_dropped"
+	case EventTxReorged:
+		return "tx_reorged"
+	case EventBalanceChanged:
+		return "balance_changed"
+	}
+
+	return "unknown"
+}
+
+// ScripthashEvent is a change of the history or balance of a subscribed scripthash.
+type ScripthashEvent struct {
+	Type       ScripthashEventType
+	Scripthash string
+	// Address is the address recorded for the scripthash when it was added, if any.
+	Address string
+
+	// TxHash and Height describe the transaction of the transaction events.
+	TxHash string
+	Height int32
+
+	// Balance and PreviousBalance are set by EventBalanceChanged.
+	Balance         GetBalanceResult
+	PreviousBalance GetBalanceResult
+}
+
+// ScripthashEvents follows the history and balance of scripthashes, and reports their
+// changes as typed events. The histories are verified like with SubscribeScripthashVerified().
+type ScripthashEvents struct {
+	verified *VerifiedSubscription
+	client   *Client
+	events   chan *ScripthashEvent
+	errors   chan error
+	balances map[string]GetBalanceResult
+	lock     sync.Mutex
+}
+
+// SubscribeScripthashEvents returns a subscription delivering the events of its scripthashes
+// until ctx is done. The transactions already in the history of a scripthash when it is added
+// are reported as new, followed by its balance. Errors are reported on Errors().
+func (s *Client) SubscribeScripthashEvents(ctx context.Context) (*ScripthashEvents, <-chan *ScripthashEvent) {
+	verified, diffs := s.SubscribeScripthashVerified(ctx)
+
+	e := &ScripthashEvents{
+		verified: verified,
+		client:   s,
+		events:   make(chan *ScripthashEvent, s.notifBuffer()),
+		errors:   make(chan error, 1),
+		balances: make(map[string]GetBalanceResult),
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.quit:
+				return
+			case err := <-verified.Errors():
+				e.notifyError(err)
+			case diff := <-diffs:
+				events, err := e.diffEvents(ctx, diff)
+				if err != nil {
+					e.notifyError(err)
+				}
+
+				for _, event := range events {
+					select {
+					case e.events <- event:
+					case <-ctx.Done():
+						return
+					case <-s.quit:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return e, e.events
+}
+
+// diffEvents returns the events of a history change, fetching the new balance of the scripthash.
+func (e *ScripthashEvents) diffEvents(ctx context.Context, diff *HistoryDiff) ([]*ScripthashEvent, error) {
+	address, _ := e.verified.GetAddress(diff.Scripthash)
+
+	var events []*ScripthashEvent
+	add := func(eventType ScripthashEventType, h *GetMempoolResult) {
+		events = append(events, &ScripthashEvent{
+			Type:       eventType,
+			Scripthash: diff.Scripthash,
+			Address:    address,
+			TxHash:     h.Hash,
+			Height:     h.Height,
+		})
+	}
+
+	for _, h := range diff.Removed {
+		if h.Height > 0 {
+			add(EventTxReorged, h)
+		} else {
+			add(EventTxDropped, h)
+		}
+	}
+	for _, h := range diff.Unconfirmed {
+		add(EventTxReorged, h)
+	}
+	for _, h := range diff.Added {
+		if h.Height > 0 {
+			add(EventTxConfirmed, h)
+		} else {
+			add(EventMempoolTx, h)
+		}
+	}
+	for _, h := range diff.Confirmed {
+		add(EventTxConfirmed, h)
+	}
+
+	balance, err := e.client.GetBalance(ctx, diff.Scripthash)
+	if err != nil {
+		return events, err
+	}
+
+	e.lock.Lock()
+	previous := e.balances[diff.Scripthash]
+	e.balances[diff.Scripthash] = balance
+	e.lock.Unlock()
+
+	if balance != previous {
+		events = append(events, &ScripthashEvent{
+			Type:            EventBalanceChanged,
+			Scripthash:      diff.Scripthash,
+			Address:         address,
+			Balance:         balance,
+			PreviousBalance: previous,
+		})
+	}
+
+	return events, nil
+}
+
+func (e *ScripthashEvents) notifyError(err error) {
+	select {
+	case e.errors <- err:
+	default:
+	}
+}
+
+// Add subscribes to scripthash, optionally recording the address it was derived from, which
+// is then set in its events.
+func (e *ScripthashEvents) Add(ctx context.Context, scripthash string, address ...string) error {
+	return e.verified.Add(ctx, scripthash, address...)
+}
+
+// AddAddress subscribes to the scripthash of address, on the network set with SetChainParams().
+func (e *ScripthashEvents) AddAddress(ctx context.Context, address string) error {
+	scripthash, err := e.client.AddressToElectrumScriptHash(address)
+	if err != nil {
+		return err
+	}
+
+	return e.Add(ctx, scripthash, address)
+}
+
+// Remove stops reporting the events of scripthash.
+func (e *ScripthashEvents) Remove(scripthash string) error {
+	e.lock.Lock()
+	delete(e.balances, scripthash)
+	e.lock.Unlock()
+
+	return e.verified.Remove(scripthash)
+}
+
+// Errors returns chan to errors encountered while following the scripthashes.
+func (e *ScripthashEvents) Errors() <-chan error {
+	return e.errors
+}
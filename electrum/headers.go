@@ -0,0 +1,257 @@
+package electrum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BlockHeaderSize is the size in bytes of a serialized block header.
+const BlockHeaderSize = 80
+
+var (
+	// ErrHeaderNotFound throws an error if a header is not available in the header store.
+	ErrHeaderNotFound = errors.New("header not found")
+
+	// ErrInvalidHeaderLength throws an error if raw headers are not a multiple of 80 bytes.
+	ErrInvalidHeaderLength = errors.New("invalid block header length")
+)
+
+// ParseBlockHeader decodes a hex encoded block header as returned by GetBlockHeader().
+func ParseBlockHeader(headerHex string) (*wire.BlockHeader, error) {
+	headers, err := ParseBlockHeaders(headerHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) != 1 {
+		return nil, ErrInvalidHeaderLength
+	}
+
+	return headers[0], nil
+}
+
+// ParseBlockHeaders decodes a hex encoded chunk of concatenated block headers as
+// returned by GetBlockHeaders().
+func ParseBlockHeaders(headersHex string) ([]*wire.BlockHeader, error) {
+	raw, err := hex.DecodeString(headersHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw)%BlockHeaderSize != 0 {
+		return nil, ErrInvalidHeaderLength
+	}
+
+	headers := make([]*wire.BlockHeader, 0, len(raw)/BlockHeaderSize)
+	for i := 0; i < len(raw); i += BlockHeaderSize {
+		header := &wire.BlockHeader{}
+		err = header.Deserialize(bytes.NewReader(raw[i : i+BlockHeaderSize]))
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+// HeaderStore persists a chain of validated block headers indexed by height.
+// Implementations must be safe for concurrent use.
+type HeaderStore interface {
+	// Tip returns the height of the highest stored header, or -1 if the store is empty.
+	Tip() (int32, error)
+	// Header returns the header at height, or ErrHeaderNotFound.
+	Header(height int32) (*wire.BlockHeader, error)
+	// Put stores headers starting at height and removes every header above them.
+	Put(height int32, headers ...*wire.BlockHeader) error
+	// Truncate removes every header above height.
+	Truncate(height int32) error
+}
+
+// MemoryHeaderStore is a HeaderStore keeping headers in memory.
+type MemoryHeaderStore struct {
+	headers map[int32]*wire.BlockHeader
+	tip     int32
+	lock    sync.RWMutex
+}
+
+// NewMemoryHeaderStore returns an empty in-memory header store.
+func NewMemoryHeaderStore() *MemoryHeaderStore {
+	return &MemoryHeaderStore{
+		headers: make(map[int32]*wire.BlockHeader),
+		tip:     -1,
+	}
+}
+
+// Tip returns the height of the highest stored header, or -1 if the store is empty.
+func (m *MemoryHeaderStore) Tip() (int32, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.tip, nil
+}
+
+// Header returns the header at height, or ErrHeaderNotFound.
+func (m *MemoryHeaderStore) Header(height int32) (*wire.BlockHeader, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	header, ok := m.headers[height]
+	if !ok {
+		return nil, ErrHeaderNotFound
+	}
+
+	return header, nil
+}
+
+// Put stores headers starting at height and removes every header above them.
+func (m *MemoryHeaderStore) Put(height int32, headers ...*wire.BlockHeader) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.truncate(height - 1)
+	for i, header := range headers {
+		m.headers[height+int32(i)] = header
+	}
+	if len(headers) > 0 {
+		m.tip = height + int32(len(headers)) - 1
+	}
+
+	return nil
+}
+
+// Truncate removes every header above height.
+func (m *MemoryHeaderStore) Truncate(height int32) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.truncate(height)
+
+	return nil
+}
+
+func (m *MemoryHeaderStore) truncate(height int32) {
+	for h := m.tip; h > height; h-- {
+		delete(m.headers, h)
+	}
+	if m.tip > height {
+		m.tip = height
+	}
+	if len(m.headers) == 0 {
+		m.tip = -1
+	}
+}
+
+// FileHeaderStore is a HeaderStore keeping headers in a flat file of 80 bytes records
+// indexed by height, the same layout Electrum uses. Heights below the first stored
+// header are left as a sparse hole.
+type FileHeaderStore struct {
+	file *os.File
+	lock sync.RWMutex
+}
+
+// NewFileHeaderStore opens or creates the header file at path.
+func NewFileHeaderStore(path string) (*FileHeaderStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileHeaderStore{file: file}, nil
+}
+
+// Close closes the underlying file.
+func (f *FileHeaderStore) Close() error {
+	return f.file.Close()
+}
+
+// Tip returns the height of the highest stored header, or -1 if the store is empty.
+func (f *FileHeaderStore) Tip() (int32, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.tip()
+}
+
+func (f *FileHeaderStore) tip() (int32, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return -1, err
+	}
+
+	return int32(info.Size()/BlockHeaderSize) - 1, nil
+}
+
+// Header returns the header at height, or ErrHeaderNotFound.
+func (f *FileHeaderStore) Header(height int32) (*wire.BlockHeader, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if height < 0 {
+		return nil, ErrHeaderNotFound
+	}
+
+	raw := make([]byte, BlockHeaderSize)
+	_, err := f.file.ReadAt(raw, int64(height)*BlockHeaderSize)
+	if err == io.EOF {
+		return nil, ErrHeaderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(raw, make([]byte, BlockHeaderSize)) {
+		return nil, ErrHeaderNotFound
+	}
+
+	header := &wire.BlockHeader{}
+	err = header.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// Put stores headers starting at height and removes every header above them.
+func (f *FileHeaderStore) Put(height int32, headers ...*wire.BlockHeader) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var buf bytes.Buffer
+	for _, header := range headers {
+		err := header.Serialize(&buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := f.file.Truncate(int64(height) * BlockHeaderSize)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.file.WriteAt(buf.Bytes(), int64(height)*BlockHeaderSize)
+	if err != nil {
+		return err
+	}
+
+	return f.file.Sync()
+}
+
+// Truncate removes every header above height.
+func (f *FileHeaderStore) Truncate(height int32) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	tip, err := f.tip()
+	if err != nil || tip <= height {
+		return err
+	}
+
+	return f.file.Truncate(int64(height+1) * BlockHeaderSize)
+}
@@ -0,0 +1,146 @@
+package electrum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlockHeader(t *testing.T) {
+	genesis := chaincfg.MainNetParams.GenesisBlock.Header
+
+	var buf bytes.Buffer
+	require.NoError(t, genesis.Serialize(&buf))
+
+	header, err := ParseBlockHeader(hex.EncodeToString(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, *chaincfg.MainNetParams.GenesisHash, header.BlockHash())
+
+	_, err = ParseBlockHeader(hex.EncodeToString(buf.Bytes()[:79]))
+	assert.ErrorIs(t, err, ErrInvalidHeaderLength)
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	genesis := chaincfg.MainNetParams.GenesisBlock.Header
+	require.NoError(t, checkProofOfWork(&genesis, &chaincfg.MainNetParams))
+
+	genesis.Nonce++
+	assert.ErrorIs(t, checkProofOfWork(&genesis, &chaincfg.MainNetParams), ErrInvalidProofOfWork)
+}
+
+func TestHeaderStores(t *testing.T) {
+	file, err := NewFileHeaderStore(filepath.Join(t.TempDir(), "headers"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	genesis := chaincfg.RegressionNetParams.GenesisBlock.Header
+
+	for _, store := range []HeaderStore{NewMemoryHeaderStore(), file} {
+		tip, err := store.Tip()
+		require.NoError(t, err)
+		assert.Equal(t, int32(-1), tip)
+
+		require.NoError(t, store.Put(10, &genesis, &genesis, &genesis))
+		tip, err = store.Tip()
+		require.NoError(t, err)
+		assert.Equal(t, int32(12), tip)
+
+		_, err = store.Header(9)
+		assert.ErrorIs(t, err, ErrHeaderNotFound)
+
+		header, err := store.Header(11)
+		require.NoError(t, err)
+		assert.Equal(t, genesis.BlockHash(), header.BlockHash())
+
+		require.NoError(t, store.Truncate(10))
+		tip, err = store.Tip()
+		require.NoError(t, err)
+		assert.Equal(t, int32(10), tip)
+
+		_, err = store.Header(11)
+		assert.ErrorIs(t, err, ErrHeaderNotFound)
+	}
+}
+
+func TestCheckDifficulty(t *testing.T) {
+	const regularBits = 0x1b0404cb
+
+	start := time.Unix(1600000000, 0)
+	header := func(bits uint32, after time.Duration) *wire.BlockHeader {
+		return &wire.BlockHeader{Bits: bits, Timestamp: start.Add(after)}
+	}
+	noLookup := func(int32) (*wire.BlockHeader, error) {
+		return nil, ErrHeaderNotFound
+	}
+
+	t.Run("mainnet", func(t *testing.T) {
+		h := &HeaderSync{params: &chaincfg.MainNetParams}
+		prev := header(regularBits, 0)
+
+		assert.NoError(t, h.checkDifficulty(5, header(regularBits, time.Hour), prev, 0, noLookup))
+		assert.ErrorIs(t, h.checkDifficulty(5, header(regularBits+1, time.Minute), prev, 0, noLookup), ErrInvalidDifficulty)
+	})
+
+	t.Run("testnet", func(t *testing.T) {
+		h := &HeaderSync{params: &chaincfg.TestNet3Params}
+		minBits := chaincfg.TestNet3Params.PowLimitBits
+		prev := header(regularBits, 0)
+
+		// The minimum difficulty is only allowed 20 minutes after the previous block.
+		assert.NoError(t, h.checkDifficulty(5, header(minBits, 21*time.Minute), prev, regularBits, noLookup))
+		assert.ErrorIs(t, h.checkDifficulty(5, header(minBits, 10*time.Minute), prev, regularBits, noLookup), ErrInvalidDifficulty)
+
+		// Blocks following a minimum difficulty block return to the last regular difficulty.
+		prev = header(minBits, 0)
+		assert.NoError(t, h.checkDifficulty(5, header(regularBits, time.Minute), prev, regularBits, noLookup))
+		assert.ErrorIs(t, h.checkDifficulty(5, header(minBits, time.Minute), prev, regularBits, noLookup), ErrInvalidDifficulty)
+
+		// Without the last regular difficulty, the check is skipped.
+		assert.NoError(t, h.checkDifficulty(5, header(regularBits+1, time.Minute), prev, 0, noLookup))
+	})
+
+	t.Run("testnet walk back", func(t *testing.T) {
+		h := &HeaderSync{params: &chaincfg.TestNet3Params}
+		minBits := chaincfg.TestNet3Params.PowLimitBits
+		headers := map[int32]*wire.BlockHeader{
+			2015: header(regularBits+1, 0),
+			2016: header(regularBits, 0),
+			2017: header(minBits, 0),
+			2018: header(minBits, 0),
+		}
+		lookup := func(height int32) (*wire.BlockHeader, error) {
+			if header, ok := headers[height]; ok {
+				return header, nil
+			}
+			return nil, ErrHeaderNotFound
+		}
+
+		bits, err := h.lastRegularBits(2018, lookup)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(regularBits), bits)
+
+		bits, err = h.lastRegularBits(2015, lookup)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(regularBits+1), bits)
+
+		bits, err = h.lastRegularBits(3000, lookup)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), bits)
+	})
+
+	t.Run("regtest", func(t *testing.T) {
+		h := &HeaderSync{params: &chaincfg.RegressionNetParams}
+		bits := chaincfg.RegressionNetParams.PowLimitBits
+		prev := header(bits, 0)
+
+		assert.NoError(t, h.checkDifficulty(2016, header(bits, time.Minute), prev, bits, noLookup))
+		assert.ErrorIs(t, h.checkDifficulty(2016, header(regularBits, time.Minute), prev, bits, noLookup), ErrInvalidDifficulty)
+	})
+}
@@ -0,0 +1,484 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// DefaultHeaderChunkSize is the number of headers requested at once while syncing,
+// which is also the maximum ElectrumX answers with.
+const DefaultHeaderChunkSize = 2016
+
+var (
+	// ErrInvalidPrevHash throws an error if a header does not link to the previous one.
+	ErrInvalidPrevHash = errors.New("header does not connect to previous header")
+
+	// ErrInvalidProofOfWork throws an error if a header hash is above its target or its
+	// target is above the network proof of work limit.
+	ErrInvalidProofOfWork = errors.New("header has invalid proof of work")
+
+	// ErrInvalidDifficulty throws an error if a header does not follow the difficulty adjustment rules.
+	ErrInvalidDifficulty = errors.New("header has unexpected difficulty")
+
+	// ErrReorgTooDeep throws an error if no common ancestor was found with the remote server chain.
+	ErrReorgTooDeep = errors.New("no common ancestor found with remote server chain")
+)
+
+// HeaderNotif reports a new tip of the local header chain.
+type HeaderNotif struct {
+	Height int32
+	Header *wire.BlockHeader
+	// Disconnected is the number of headers removed by a reorganization before reaching this tip.
+	Disconnected int32
+}
+
+// HeaderSync downloads block headers from the remote server, validates their linkage and
+// proof of work against the network parameters and persists them in a HeaderStore.
+type HeaderSync struct {
+	client *Client
+	params *chaincfg.Params
+	store  HeaderStore
+
+	// ChunkSize is the number of headers requested at once.
+	ChunkSize uint32
+	// MaxReorgDepth is the number of headers walked back looking for a common ancestor.
+	MaxReorgDepth int32
+	// Checkpoint is the trusted header an empty store is started from, instead of genesis.
+	Checkpoint *Checkpoint
+
+	errors chan error
+	lock   sync.Mutex
+}
+
+// NewHeaderSync initialize a new header sync for the network described by params.
+func NewHeaderSync(client *Client, params *chaincfg.Params, store HeaderStore) *HeaderSync {
+	return &HeaderSync{
+		client:        client,
+		params:        params,
+		store:         store,
+		ChunkSize:     DefaultHeaderChunkSize,
+		MaxReorgDepth: DefaultHeaderChunkSize,
+		errors:        make(chan error, 1),
+	}
+}
+
+// Errors returns chan to errors encountered while following the remote server.
+func (h *HeaderSync) Errors() <-chan error {
+	return h.errors
+}
+
+// Tip returns the height and header of the local chain tip.
+func (h *HeaderSync) Tip() (int32, *wire.BlockHeader, error) {
+	height, err := h.store.Tip()
+	if err != nil {
+		return -1, nil, err
+	}
+
+	header, err := h.store.Header(height)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return height, header, nil
+}
+
+// Sync downloads and validates every header between the local tip and the remote server
+// tip, rolling back the local chain if the remote server has reorganized.
+func (h *HeaderSync) Sync(ctx context.Context) (*HeaderNotif, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.sync(ctx, -1)
+}
+
+func (h *HeaderSync) sync(ctx context.Context, remoteHeight int32) (*HeaderNotif, error) {
+	err := h.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tip, err := h.store.Tip()
+	if err != nil {
+		return nil, err
+	}
+
+	// The remote tip may be lower than ours, check we are still on the same chain.
+	notif := &HeaderNotif{}
+	if remoteHeight >= 0 && remoteHeight <= tip {
+		notif.Disconnected, err = h.rollback(ctx, remoteHeight)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		tip, err = h.store.Tip()
+		if err != nil {
+			return nil, err
+		}
+
+		var headers []*wire.BlockHeader
+		headers, err = h.fetch(ctx, tip+1, h.ChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(headers) == 0 {
+			break
+		}
+
+		var prev *wire.BlockHeader
+		prev, err = h.store.Header(tip)
+		if err != nil {
+			return nil, err
+		}
+
+		if headers[0].PrevBlock != prev.BlockHash() {
+			var disconnected int32
+			disconnected, err = h.rollback(ctx, tip)
+			if err != nil {
+				return nil, err
+			}
+			// The remote server headers do not link to its own header at the tip.
+			if disconnected == 0 {
+				return nil, ErrInvalidPrevHash
+			}
+
+			notif.Disconnected += disconnected
+			continue
+		}
+
+		err = h.validate(tip+1, headers)
+		if err != nil {
+			return nil, err
+		}
+
+		err = h.store.Put(tip+1, headers...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notif.Height, notif.Header, err = h.Tip()
+	if err != nil {
+		return nil, err
+	}
+
+	return notif, nil
+}
+
+// init seeds an empty store with the checkpoint header, or the genesis header.
+func (h *HeaderSync) init(ctx context.Context) error {
+	tip, err := h.store.Tip()
+	if err != nil || tip >= 0 {
+		return err
+	}
+
+	if h.Checkpoint == nil || h.Checkpoint.Height == 0 {
+		return h.store.Put(0, &h.params.GenesisBlock.Header)
+	}
+
+	result, err := h.client.GetBlockHeader(ctx, h.Checkpoint.Height, h.Checkpoint.Height)
+	if err != nil {
+		return err
+	}
+
+	header, err := ParseBlockHeader(result.Header)
+	if err != nil {
+		return err
+	}
+
+	err = VerifyHeaderProof(header, h.Checkpoint.Height, result.Branch, h.Checkpoint.Root)
+	if err != nil {
+		return err
+	}
+
+	return h.store.Put(int32(h.Checkpoint.Height), header)
+}
+
+func (h *HeaderSync) fetch(ctx context.Context, height int32, count uint32) ([]*wire.BlockHeader, error) {
+	resp, err := h.client.GetBlockHeaders(ctx, uint32(height), count)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := ParseBlockHeaders(resp.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(headers)) != resp.Count {
+		return nil, ErrInvalidHeaderLength
+	}
+
+	return headers, nil
+}
+
+// rollback walks back from height until the local and remote chains agree and removes
+// every local header above the common ancestor. It returns the number of headers removed.
+func (h *HeaderSync) rollback(ctx context.Context, height int32) (int32, error) {
+	tip, err := h.store.Tip()
+	if err != nil {
+		return 0, err
+	}
+
+	for top := height; top >= 0 && height-top < h.MaxReorgDepth; {
+		start := top - int32(h.ChunkSize) + 1
+		if start < 0 {
+			start = 0
+		}
+
+		remote, err := h.fetch(ctx, start, uint32(top-start+1))
+		if err != nil {
+			return 0, err
+		}
+
+		for i := len(remote) - 1; i >= 0; i-- {
+			local, err := h.store.Header(start + int32(i))
+			if errors.Is(err, ErrHeaderNotFound) {
+				return 0, ErrReorgTooDeep
+			}
+			if err != nil {
+				return 0, err
+			}
+
+			if local.BlockHash() == remote[i].BlockHash() {
+				ancestor := start + int32(i)
+
+				err = h.store.Truncate(ancestor)
+				if err != nil {
+					return 0, err
+				}
+
+				return tip - ancestor, nil
+			}
+		}
+
+		top = start - 1
+	}
+
+	return 0, ErrReorgTooDeep
+}
+
+// validate checks headers starting at height connect to each other and to the stored
+// chain, and carry a valid proof of work.
+func (h *HeaderSync) validate(height int32, headers []*wire.BlockHeader) error {
+	lookup := func(at int32) (*wire.BlockHeader, error) {
+		if at >= height && at < height+int32(len(headers)) {
+			return headers[at-height], nil
+		}
+		return h.store.Header(at)
+	}
+
+	// lastBits is the difficulty of the last header not mined under the minimum difficulty
+	// rule, on the networks allowing it.
+	var lastBits uint32
+	if h.params.ReduceMinDifficulty {
+		var err error
+		lastBits, err = h.lastRegularBits(height-1, lookup)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, header := range headers {
+		at := height + int32(i)
+
+		prev, err := lookup(at - 1)
+		if err != nil {
+			return err
+		}
+
+		if header.PrevBlock != prev.BlockHash() {
+			return fmt.Errorf("height %d: %w", at, ErrInvalidPrevHash)
+		}
+
+		err = checkProofOfWork(header, h.params)
+		if err != nil {
+			return fmt.Errorf("height %d: %w", at, err)
+		}
+
+		err = h.checkDifficulty(at, header, prev, lastBits, lookup)
+		if err != nil {
+			return fmt.Errorf("height %d: %w", at, err)
+		}
+
+		if at%h.retargetInterval() == 0 || header.Bits != h.params.PowLimitBits {
+			lastBits = header.Bits
+		}
+	}
+
+	return nil
+}
+
+func (h *HeaderSync) retargetInterval() int32 {
+	return int32(h.params.TargetTimespan / h.params.TargetTimePerBlock)
+}
+
+// lastRegularBits walks back from height to the last header not mined under the minimum
+// difficulty rule, either a retarget header or a header above the minimum difficulty, and
+// returns its difficulty, or 0 if it is not stored.
+func (h *HeaderSync) lastRegularBits(height int32, lookup func(int32) (*wire.BlockHeader, error)) (uint32, error) {
+	interval := h.retargetInterval()
+
+	for ; height >= 0; height-- {
+		header, err := lookup(height)
+		if errors.Is(err, ErrHeaderNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if height%interval == 0 || header.Bits != h.params.PowLimitBits {
+			return header.Bits, nil
+		}
+	}
+
+	return h.params.PowLimitBits, nil
+}
+
+// checkProofOfWork ensures the header hash is below its target and the target is below
+// the network proof of work limit.
+func checkProofOfWork(header *wire.BlockHeader, params *chaincfg.Params) error {
+	target := blockchain.CompactToBig(header.Bits)
+	if target.Sign() <= 0 || target.Cmp(params.PowLimit) > 0 {
+		return ErrInvalidProofOfWork
+	}
+
+	hash := header.BlockHash()
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return ErrInvalidProofOfWork
+	}
+
+	return nil
+}
+
+// checkDifficulty enforces the difficulty adjustment rules. On the networks allowing minimum
+// difficulty blocks, a header more than MinDiffReductionTime after the previous one may have
+// the minimum difficulty, the others having lastBits, the difficulty of the last header mined
+// without this rule. The regression test network never retargets. Checks needing headers
+// which are not stored, below a checkpoint, are skipped.
+func (h *HeaderSync) checkDifficulty(height int32, header, prev *wire.BlockHeader, lastBits uint32,
+	lookup func(int32) (*wire.BlockHeader, error)) error {
+
+	interval := h.retargetInterval()
+	if height%interval != 0 {
+		expected := prev.Bits
+		if h.params.ReduceMinDifficulty {
+			minDiffTime := prev.Timestamp.Add(h.params.MinDiffReductionTime)
+			if header.Timestamp.After(minDiffTime) {
+				expected = h.params.PowLimitBits
+			} else if expected = lastBits; expected == 0 {
+				return nil
+			}
+		}
+
+		if header.Bits != expected {
+			return ErrInvalidDifficulty
+		}
+		return nil
+	}
+
+	if h.params.PoWNoRetargeting {
+		if header.Bits != prev.Bits {
+			return ErrInvalidDifficulty
+		}
+		return nil
+	}
+
+	first, err := lookup(height - interval)
+	if errors.Is(err, ErrHeaderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	targetTimespan := int64(h.params.TargetTimespan / time.Second)
+	minTimespan := targetTimespan / h.params.RetargetAdjustmentFactor
+	maxTimespan := targetTimespan * h.params.RetargetAdjustmentFactor
+
+	actualTimespan := prev.Timestamp.Unix() - first.Timestamp.Unix()
+	if actualTimespan < minTimespan {
+		actualTimespan = minTimespan
+	} else if actualTimespan > maxTimespan {
+		actualTimespan = maxTimespan
+	}
+
+	newTarget := new(big.Int).Mul(blockchain.CompactToBig(prev.Bits), big.NewInt(actualTimespan))
+	newTarget.Div(newTarget, big.NewInt(targetTimespan))
+	if newTarget.Cmp(h.params.PowLimit) > 0 {
+		newTarget.Set(h.params.PowLimit)
+	}
+
+	if header.Bits != blockchain.BigToCompact(newTarget) {
+		return ErrInvalidDifficulty
+	}
+
+	return nil
+}
+
+// Follow syncs the local chain, then keeps it in sync with the remote server by following
+// the headers notifications, including reorganizations. A notification is sent every
+// time the local tip changes, until ctx is done. Errors are reported on Errors().
+func (h *HeaderSync) Follow(ctx context.Context) (<-chan *HeaderNotif, error) {
+	notif, err := h.Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := h.client.SubscribeHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notifChan := make(chan *HeaderNotif, 1)
+	notifChan <- notif
+
+	go func() {
+		defer close(notifChan)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case remote, ok := <-headers:
+				if !ok {
+					return
+				}
+
+				h.lock.Lock()
+				prevTip, _ := h.store.Tip()
+				notif, err := h.sync(ctx, remote.Height)
+				h.lock.Unlock()
+
+				if err != nil {
+					select {
+					case h.errors <- err:
+					default:
+					}
+					continue
+				}
+
+				if notif.Height == prevTip && notif.Disconnected == 0 {
+					continue
+				}
+
+				select {
+				case notifChan <- notif:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return notifChan, nil
+}
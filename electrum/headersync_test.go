@@ -0,0 +1,178 @@
+package electrum_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHeaderSync(t *testing.T) (*electrumtest.Chain, *electrum.HeaderSync, electrum.HeaderStore) {
+	server := electrumtest.NewServer()
+	t.Cleanup(server.Close)
+	chain := electrumtest.NewChain(server)
+
+	client, err := server.NewClient(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(client.Shutdown)
+
+	store := electrum.NewMemoryHeaderStore()
+	sync := electrum.NewHeaderSync(client, chain.Params(), store)
+	sync.ChunkSize = 4
+
+	return chain, sync, store
+}
+
+func assertTip(t *testing.T, chain *electrumtest.Chain, notif *electrum.HeaderNotif) {
+	t.Helper()
+
+	expected, err := chain.Header(chain.Height())
+	require.NoError(t, err)
+	assert.Equal(t, chain.Height(), notif.Height)
+	assert.Equal(t, expected.BlockHash(), notif.Header.BlockHash())
+}
+
+func TestHeaderSync(t *testing.T) {
+	chain, sync, store := newHeaderSync(t)
+	chain.Mine(10)
+
+	notif, err := sync.Sync(context.Background())
+	require.NoError(t, err)
+	assertTip(t, chain, notif)
+	assert.Equal(t, int32(0), notif.Disconnected)
+
+	// The remote chain switches to a longer branch forking 3 blocks below its tip.
+	require.NoError(t, chain.Reorg(3))
+	chain.Mine(5)
+
+	notif, err = sync.Sync(context.Background())
+	require.NoError(t, err)
+	assertTip(t, chain, notif)
+	assert.Equal(t, int32(3), notif.Disconnected)
+
+	for height := int32(0); height <= chain.Height(); height++ {
+		header, err := chain.Header(height)
+		require.NoError(t, err)
+
+		local, err := store.Header(height)
+		require.NoError(t, err)
+		assert.Equal(t, header.BlockHash(), local.BlockHash(), "height %d", height)
+	}
+}
+
+func TestHeaderSyncReorgTooDeep(t *testing.T) {
+	chain, sync, _ := newHeaderSync(t)
+	chain.Mine(10)
+
+	_, err := sync.Sync(context.Background())
+	require.NoError(t, err)
+
+	sync.MaxReorgDepth = 2
+	require.NoError(t, chain.Reorg(5))
+	chain.Mine(6)
+
+	_, err = sync.Sync(context.Background())
+	assert.ErrorIs(t, err, electrum.ErrReorgTooDeep)
+}
+
+func TestHeaderSyncUnlinkedHeaders(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+	chain := electrumtest.NewChain(server)
+	chain.Mine(5)
+
+	client, err := server.NewClient(context.Background())
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	sync := electrum.NewHeaderSync(client, chain.Params(), electrum.NewMemoryHeaderStore())
+	sync.ChunkSize = 4
+
+	_, err = sync.Sync(context.Background())
+	require.NoError(t, err)
+
+	// The remote server answers with headers above the tip not linking to the tip it
+	// reports, so no rollback can reconcile the chains.
+	chain.Mine(2)
+	server.Handle("blockchain.block.headers", func(params []json.RawMessage) (interface{}, error) {
+		var start, count uint32
+		if err := json.Unmarshal(params[0], &start); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(params[1], &count); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		var n uint32
+		for height := int32(start); n < count && height <= chain.Height(); height++ {
+			header, err := chain.Header(height)
+			if err != nil {
+				return nil, err
+			}
+			if height == 6 {
+				header.PrevBlock = chainhash.Hash{}
+			}
+			if err := header.Serialize(&buf); err != nil {
+				return nil, err
+			}
+			n++
+		}
+
+		return &electrum.GetBlockHeadersResult{Count: n, Headers: hex.EncodeToString(buf.Bytes()), Max: 2016}, nil
+	})
+
+	_, err = sync.Sync(context.Background())
+	assert.ErrorIs(t, err, electrum.ErrInvalidPrevHash)
+}
+
+func TestHeaderSyncFollow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chain, sync, _ := newHeaderSync(t)
+	chain.Mine(3)
+
+	notifs, err := sync.Follow(ctx)
+	require.NoError(t, err)
+
+	receive := func() *electrum.HeaderNotif {
+		t.Helper()
+
+		select {
+		case notif := <-notifs:
+			return notif
+		case err := <-sync.Errors():
+			t.Fatal(err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("header notification not received")
+		}
+		return nil
+	}
+
+	assertTip(t, chain, receive())
+
+	chain.Mine(2)
+	assertTip(t, chain, receive())
+
+	require.NoError(t, chain.Reorg(2))
+	chain.Mine(3)
+
+	// The disconnection and the new branch may be notified separately.
+	var notif *electrum.HeaderNotif
+	var disconnected int32
+	for notif == nil || notif.Height < chain.Height() {
+		notif = receive()
+		disconnected += notif.Disconnected
+	}
+	assertTip(t, chain, notif)
+	assert.Equal(t, int32(2), disconnected)
+}
@@ -0,0 +1,141 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// RequestInfo describes a request sent to the remote server, as seen by Hooks.
+type RequestInfo struct {
+	Method string
+	ID     uint64
+	Server string
+	// Size is the size in bytes of the request.
+	Size  int
+	Start time.Time
+}
+
+// ResponseInfo describes the outcome of a request, as seen by Hooks.
+type ResponseInfo struct {
+	Latency time.Duration
+	// Size is the size in bytes of the response, 0 if none was received.
+	Size int
+	Err  error
+}
+
+// NotificationInfo describes a notification received from the remote server, as seen by Hooks.
+type NotificationInfo struct {
+	Method string
+	Server string
+	Size   int
+}
+
+// Hooks instruments a client, see WithHooks(). The methods are called synchronously from the
+// client and must not block.
+type Hooks interface {
+	// OnRequestStart is called before a request is sent, and returns the context used for the
+	// rest of the request.
+	OnRequestStart(ctx context.Context, req *RequestInfo) context.Context
+
+	// OnRequestEnd is called once the request has been answered or has failed.
+	OnRequestEnd(ctx context.Context, req *RequestInfo, resp *ResponseInfo)
+
+	// OnNotification is called for every notification received.
+	OnNotification(notif *NotificationInfo)
+
+	// OnNotificationDropped is called when a notification is dropped because a subscription
+	// did not keep up.
+	OnNotificationDropped(notif *NotificationInfo)
+}
+
+type multiHooks []Hooks
+
+func (m multiHooks) OnRequestStart(ctx context.Context, req *RequestInfo) context.Context {
+	for _, h := range m {
+		ctx = h.OnRequestStart(ctx, req)
+	}
+
+	return ctx
+}
+
+func (m multiHooks) OnRequestEnd(ctx context.Context, req *RequestInfo, resp *ResponseInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].OnRequestEnd(ctx, req, resp)
+	}
+}
+
+func (m multiHooks) OnNotification(notif *NotificationInfo) {
+	for _, h := range m {
+		h.OnNotification(notif)
+	}
+}
+
+func (m multiHooks) OnNotificationDropped(notif *NotificationInfo) {
+	for _, h := range m {
+		h.OnNotificationDropped(notif)
+	}
+}
+
+// ErrorType classifies a request error for instrumentation: "" for no error, "timeout",
+// "shutdown", "connection", "decode", "rpc" for errors returned by the remote server, or "other".
+func ErrorType(err error) string {
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var rpcErr *RPCError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &rpcErr):
+		return "rpc"
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	case errors.Is(err, ErrServerShutdown), errors.Is(err, ErrReconnectFailed):
+		return "shutdown"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &netErr):
+		return "connection"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "decode"
+	}
+
+	return "other"
+}
+
+// requestStart calls the OnRequestStart hooks of the client, if any.
+func (s *Client) requestStart(ctx context.Context, method string, id uint64, size int) (context.Context, *RequestInfo) {
+	req := &RequestInfo{
+		Method: method,
+		ID:     id,
+		Size:   size,
+		Start:  time.Now(),
+	}
+	if s.hooks == nil {
+		return ctx, req
+	}
+
+	req.Server = s.serverAddr()
+
+	return s.hooks.OnRequestStart(ctx, req), req
+}
+
+// requestEnd calls the OnRequestEnd hooks of the client, if any.
+func (s *Client) requestEnd(ctx context.Context, req *RequestInfo, resp *container, err error) {
+	if s.hooks == nil {
+		return
+	}
+
+	info := &ResponseInfo{
+		Latency: time.Since(req.Start),
+		Err:     err,
+	}
+	if resp != nil {
+		info.Size = len(resp.content)
+	}
+
+	s.hooks.OnRequestEnd(ctx, req, info)
+}
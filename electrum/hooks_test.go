@@ -0,0 +1,76 @@
+package electrum
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordHooks struct {
+	started []string
+	ended   map[string]*ResponseInfo
+	lock    sync.Mutex
+}
+
+func (h *recordHooks) OnRequestStart(ctx context.Context, req *RequestInfo) context.Context {
+	h.lock.Lock()
+	h.started = append(h.started, req.Method)
+	h.lock.Unlock()
+
+	return ctx
+}
+
+func (h *recordHooks) OnRequestEnd(ctx context.Context, req *RequestInfo, resp *ResponseInfo) {
+	h.lock.Lock()
+	h.ended[req.Method] = resp
+	h.lock.Unlock()
+}
+
+func (h *recordHooks) OnNotification(notif *NotificationInfo) {}
+
+func (h *recordHooks) OnNotificationDropped(notif *NotificationInfo) {}
+
+func TestClientHooks(t *testing.T) {
+	addr := serveOnce(t, func(req *request) interface{} {
+		switch req.Method {
+		case "server.version":
+			return []string{"ElectrumX 1.16.0", "1.4"}
+		case "blockchain.scripthash.get_balance":
+			return map[string]int64{"confirmed": 1000, "unconfirmed": 0}
+		case "server.banner":
+			return "banner"
+		}
+		return nil
+	})
+
+	hooks := &recordHooks{ended: make(map[string]*ResponseInfo)}
+	client, err := NewClient(context.Background(), addr, WithHooks(hooks))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, err = client.ServerBanner(context.Background())
+	require.NoError(t, err)
+
+	batch := client.Batch()
+	batch.GetBalance("8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161")
+	require.NoError(t, batch.Send(context.Background()))
+
+	hooks.lock.Lock()
+	defer hooks.lock.Unlock()
+
+	assert.Equal(t, []string{"server.version", "server.banner", "blockchain.scripthash.get_balance"}, hooks.started)
+	require.Contains(t, hooks.ended, "server.banner")
+	assert.NoError(t, hooks.ended["server.banner"].Err)
+	assert.Greater(t, hooks.ended["server.banner"].Size, 0)
+	require.Contains(t, hooks.ended, "blockchain.scripthash.get_balance")
+	assert.NoError(t, hooks.ended["blockchain.scripthash.get_balance"].Err)
+}
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, "", ErrorType(nil))
+	assert.Equal(t, "timeout", ErrorType(ErrTimeout))
+	assert.Equal(t, "shutdown", ErrorType(ErrServerShutdown))
+}
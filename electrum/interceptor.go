@@ -0,0 +1,109 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Invoker sends a call to the remote server and decodes the response into v.
+type Invoker func(ctx context.Context, method string, params []interface{}, v interface{}) error
+
+// Interceptor wraps every call of a client, see WithInterceptors(). It may inspect or modify
+// the method and params, call invoker any number of times, and inspect v once it returns, v
+// being the decoded response. The calls of a batch go through the interceptors concurrently,
+// each in its own goroutine, v being then a *json.RawMessage receiving the whole response,
+// see Batch.Send().
+type Interceptor func(ctx context.Context, method string, params []interface{}, v interface{}, invoker Invoker) error
+
+// ChainInterceptors combines interceptors into one, the first being the outermost.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, method string, params []interface{}, v interface{}, invoker Invoker) error {
+		return chainInvoker(interceptors, invoker)(ctx, method, params, v)
+	}
+}
+
+func chainInvoker(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, method string, params []interface{}, v interface{}) error {
+			return interceptor(ctx, method, params, v, next)
+		}
+	}
+
+	return invoker
+}
+
+// nonIdempotentMethods lists the calls that must not be sent twice.
+var nonIdempotentMethods = map[string]bool{
+	"blockchain.transaction.broadcast": true,
+	"server.add_peer":                  true,
+	"server.version":                   true,
+}
+
+// RetryConfig controls how RetryInterceptor() retries failed calls.
+type RetryConfig struct {
+	// MaxAttempts is the number of attempts of a call, including the first one.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled after every attempt.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryConfig returns the retry settings used when none are provided.
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxAttempts: 3,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+	}
+}
+
+// RetryInterceptor retries the calls failing with a connection error, typically while a
+// reconnecting client redials. Broadcasts and other calls that must not be sent twice are
+// never retried, nor are calls of a client that has been shut down.
+func RetryInterceptor(config *RetryConfig) Interceptor {
+	if config == nil {
+		config = DefaultRetryConfig()
+	}
+
+	return func(ctx context.Context, method string, params []interface{}, v interface{}, invoker Invoker) error {
+		backoff := config.Backoff
+
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = invoker(ctx, method, params, v)
+			if err == nil || nonIdempotentMethods[method] || attempt >= config.MaxAttempts ||
+				!isConnectionError(err) || errors.Is(err, ErrServerShutdown) {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if config.MaxBackoff > 0 && backoff > config.MaxBackoff {
+				backoff = config.MaxBackoff
+			}
+		}
+	}
+}
+
+// RateLimitInterceptor delays calls so that they are sent at the rate allowed by limiter,
+// to stay below the limits of public servers. Calls whose context ends while waiting fail
+// with ErrTimeout.
+func RateLimitInterceptor(limiter *rate.Limiter) Interceptor {
+	return func(ctx context.Context, method string, params []interface{}, v interface{}, invoker Invoker) error {
+		if err := limiter.Wait(ctx); err != nil {
+			return ErrTimeout
+		}
+
+		return invoker(ctx, method, params, v)
+	}
+}
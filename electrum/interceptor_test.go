@@ -0,0 +1,53 @@
+package electrum
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainInterceptors(t *testing.T) {
+	var order []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, method string, params []interface{}, v interface{}, invoker Invoker) error {
+			order = append(order, name)
+			return invoker(ctx, method, append(params, name), v)
+		}
+	}
+
+	var resp basicResp
+	invoker := func(ctx context.Context, method string, params []interface{}, v interface{}) error {
+		assert.Equal(t, []interface{}{"first", "second"}, params)
+		v.(*basicResp).Result = method
+		return nil
+	}
+
+	err := ChainInterceptors(record("first"), record("second"))(context.Background(), "server.banner", nil, &resp, invoker)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, order)
+	assert.Equal(t, "server.banner", resp.Result)
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	retry := RetryInterceptor(&RetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	attempts := 0
+	invoker := func(ctx context.Context, method string, params []interface{}, v interface{}) error {
+		attempts++
+		if attempts < 3 {
+			return io.EOF
+		}
+		return nil
+	}
+
+	assert.NoError(t, retry(context.Background(), "server.banner", nil, nil, invoker))
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err := retry(context.Background(), "blockchain.transaction.broadcast", nil, nil, invoker)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,124 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrKeepAliveTimeout throws an error if the remote server stopped answering keepalive pings.
+	ErrKeepAliveTimeout = errors.New("remote server missed keepalive pings")
+)
+
+// KeepAliveConfig controls how a client pings the remote server to keep an idle connection
+// open, and to detect a dead one.
+type KeepAliveConfig struct {
+	// Interval is how long the connection must be idle before a ping is sent.
+	Interval time.Duration
+	// Timeout bounds the wait for a single pong, Interval by default.
+	Timeout time.Duration
+	// MaxMissed is the number of consecutive missed pongs after which the connection is
+	// considered dead, 1 by default.
+	MaxMissed int
+}
+
+// DefaultKeepAliveConfig returns the keepalive settings used when none are provided.
+func DefaultKeepAliveConfig() *KeepAliveConfig {
+	return &KeepAliveConfig{
+		Interval:  time.Minute,
+		Timeout:   10 * time.Second,
+		MaxMissed: 2,
+	}
+}
+
+// Latency returns the round-trip time of the last answered keepalive ping, or 0 if none has
+// been answered yet.
+func (s *Client) Latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.latency))
+}
+
+// idle returns how long ago the last message was received from the remote server.
+func (s *Client) idle() time.Duration {
+	last := atomic.LoadInt64(&s.lastReceived)
+	if last == 0 {
+		return 0
+	}
+
+	return time.Since(time.Unix(0, last))
+}
+
+// keepAlive pings the remote server whenever the connection has been idle for the configured
+// interval, until the client is shut down. Once too many pongs are missed, the connection is
+// reported dead with ErrKeepAliveTimeout on Error, then redialed if the client reconnects, or
+// shut down otherwise.
+func (s *Client) keepAlive(config *KeepAliveConfig) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = config.Interval
+	}
+	maxMissed := config.MaxMissed
+	if maxMissed <= 0 {
+		maxMissed = 1
+	}
+
+	timer := time.NewTimer(config.Interval)
+	defer timer.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-timer.C:
+		}
+
+		if idle := s.idle(); idle < config.Interval && missed == 0 {
+			timer.Reset(config.Interval - idle)
+			continue
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := s.Ping(ctx)
+		cancel()
+
+		switch {
+		case err == nil:
+			missed = 0
+			atomic.StoreInt64(&s.latency, int64(time.Since(start)))
+
+		case s.IsShutdown():
+			return
+
+		case errors.Is(err, ErrTimeout):
+			missed++
+			if missed >= maxMissed {
+				missed = 0
+				s.keepAliveFailed()
+			}
+
+		default:
+			s.notifyError(err)
+		}
+
+		timer.Reset(config.Interval)
+	}
+}
+
+// keepAliveFailed drops a connection that stopped answering pings.
+func (s *Client) keepAliveFailed() {
+	s.log(LogWarn, "keepalive pings missed, dropping connection")
+	s.notifyError(ErrKeepAliveTimeout)
+
+	if s.reconnect != nil && s.dialer != nil {
+		// Closing the transport makes the listen loop redial.
+		if transport := s.getTransport(); transport != nil {
+			_ = transport.Close()
+		}
+		return
+	}
+
+	s.Shutdown()
+}
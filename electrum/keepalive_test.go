@@ -0,0 +1,140 @@
+package electrum
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveOnce accepts a single connection on a local listener and answers every request with
+// the result returned by answer, or not at all when answer returns nil. Batches are answered
+// with a batch.
+func serveOnce(t *testing.T, answer func(req *request) interface{}) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadBytes(nl)
+			if err != nil {
+				return
+			}
+
+			var reqs []*request
+			batch := bytesTrimLeft(line)[0] == '['
+			if batch {
+				err = json.Unmarshal(line, &reqs)
+			} else {
+				req := &request{}
+				err = json.Unmarshal(line, req)
+				reqs = append(reqs, req)
+			}
+			if err != nil {
+				return
+			}
+
+			var resps []string
+			for _, req := range reqs {
+				if result := answer(req); result != nil {
+					bytes, _ := json.Marshal(result)
+					resps = append(resps, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, bytes))
+				}
+			}
+			if len(resps) == 0 {
+				continue
+			}
+
+			if batch {
+				_, _ = fmt.Fprintf(conn, "[%s]\n", strings.Join(resps, ","))
+			} else {
+				_, _ = fmt.Fprintf(conn, "%s\n", resps[0])
+			}
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestKeepAliveLatency(t *testing.T) {
+	addr := serveOnce(t, func(req *request) interface{} {
+		if req.Method == "server.version" {
+			return []string{"ElectrumX 1.16.0", "1.4"}
+		}
+		return json.RawMessage("null")
+	})
+
+	client, err := NewClient(context.Background(), addr, WithKeepAlive(10*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	assert.Eventually(t, func() bool {
+		return client.Latency() > 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestKeepAliveMissedPongs(t *testing.T) {
+	addr := serveOnce(t, func(req *request) interface{} {
+		if req.Method == "server.version" {
+			return []string{"ElectrumX 1.16.0", "1.4"}
+		}
+		return nil
+	})
+
+	client, err := NewClient(context.Background(), addr, WithKeepAliveConfig(&KeepAliveConfig{
+		Interval:  10 * time.Millisecond,
+		Timeout:   10 * time.Millisecond,
+		MaxMissed: 2,
+	}))
+	require.NoError(t, err)
+
+	select {
+	case err := <-client.Error:
+		assert.ErrorIs(t, err, ErrKeepAliveTimeout)
+	case <-time.After(time.Second):
+		t.Fatal("keepalive timeout not reported")
+	}
+
+	assert.Eventually(t, client.IsShutdown, time.Second, 5*time.Millisecond)
+}
+
+func TestKeepAliveErrorLateReader(t *testing.T) {
+	addr := serveOnce(t, func(req *request) interface{} {
+		if req.Method == "server.version" {
+			return []string{"ElectrumX 1.16.0", "1.4"}
+		}
+		return nil
+	})
+
+	client, err := NewClient(context.Background(), addr, WithKeepAliveConfig(&KeepAliveConfig{
+		Interval:  10 * time.Millisecond,
+		Timeout:   10 * time.Millisecond,
+		MaxMissed: 2,
+	}))
+	require.NoError(t, err)
+
+	// The failure is reported before anybody reads the Error channel.
+	assert.Eventually(t, client.IsShutdown, time.Second, 5*time.Millisecond)
+
+	select {
+	case err := <-client.Error:
+		assert.ErrorIs(t, err, ErrKeepAliveTimeout)
+	default:
+		t.Fatal("keepalive timeout lost")
+	}
+}
@@ -0,0 +1,162 @@
+package electrum
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+const (
+	// LogDebug is used for every message exchanged with the remote server.
+	LogDebug LogLevel = iota
+
+	// LogInfo is used for connection events.
+	LogInfo
+
+	// LogWarn is used for unexpected messages received from the remote server.
+	LogWarn
+
+	// LogError is used for failures the client cannot recover from.
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	}
+
+	return "UNKNOWN"
+}
+
+// LogField is a key value pair attached to a log entry. The client uses the method, id,
+// server, latency, size, params, payload and error keys.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives the structured log entries of a client.
+type Logger interface {
+	Log(level LogLevel, msg string, fields ...LogField)
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger returns a Logger writing entries at or above level to logger, or to the
+// standard logger when nil, in the "LEVEL msg key=value" form.
+func NewStdLogger(logger *log.Logger, level LogLevel) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &stdLogger{logger: logger, level: level}
+}
+
+func (l *stdLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	if level < l.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for _, field := range fields {
+		fmt.Fprintf(&b, " %s=%v", field.Key, field.Value)
+	}
+
+	l.logger.Print(b.String())
+}
+
+// Redaction selects the payloads hidden from the log entries of a client.
+type Redaction uint8
+
+const (
+	// RedactTransactions hides the params and results of blockchain.transaction calls, such
+	// as raw transactions being broadcast.
+	RedactTransactions Redaction = 1 << iota
+
+	// RedactAddresses hides the params, results and notifications of blockchain.scripthash
+	// calls, which identify the addresses of a wallet.
+	RedactAddresses
+
+	// RedactPayloads hides the params and results of every call.
+	RedactPayloads Redaction = 0xff
+)
+
+const redacted = "[redacted]"
+
+// redacts reports whether the payloads of method must be hidden.
+func (r Redaction) redacts(method string) bool {
+	switch {
+	case r == RedactPayloads:
+		return true
+	case r&RedactTransactions != 0 && strings.HasPrefix(method, "blockchain.transaction."):
+		return true
+	case r&RedactAddresses != 0 && strings.HasPrefix(method, "blockchain.scripthash."):
+		return true
+	}
+
+	return false
+}
+
+// debugLogger is the logger of the clients without logger in DebugMode.
+var debugLogger = NewStdLogger(nil, LogDebug)
+
+// getLogger returns the logger of the client, the standard logger in DebugMode, or nil.
+func (s *Client) getLogger() Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	if DebugMode {
+		return debugLogger
+	}
+
+	return nil
+}
+
+// log writes an entry tagged with the remote server address.
+func (s *Client) log(level LogLevel, msg string, fields ...LogField) {
+	logger := s.getLogger()
+	if logger == nil {
+		return
+	}
+
+	logger.Log(level, msg, append([]LogField{{"server", s.serverAddr()}}, fields...)...)
+}
+
+// payloadField returns the field logging payload, hidden if the payloads of method are redacted.
+func (s *Client) payloadField(key, method string, payload interface{}) LogField {
+	if s.redaction.redacts(method) {
+		return LogField{key, redacted}
+	}
+	if bytes, ok := payload.([]byte); ok {
+		payload = strings.TrimRight(string(bytes), "\n")
+	}
+
+	return LogField{key, payload}
+}
+
+// serverAddr returns the address of the remote server, when the transport exposes it.
+func (s *Client) serverAddr() string {
+	transport, ok := s.getTransport().(interface{ RemoteAddr() net.Addr })
+	if !ok {
+		return ""
+	}
+
+	return transport.RemoteAddr().String()
+}
@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package electrum
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to logger, or to the default slog logger when nil.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	var slogLevel slog.Level
+	switch level {
+	case LogDebug:
+		slogLevel = slog.LevelDebug
+	case LogInfo:
+		slogLevel = slog.LevelInfo
+	case LogWarn:
+		slogLevel = slog.LevelWarn
+	default:
+		slogLevel = slog.LevelError
+	}
+
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+
+	l.logger.LogAttrs(ctx, slogLevel, msg, attrs...)
+}
@@ -0,0 +1,107 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	level  LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordLogger struct {
+	entries []logEntry
+	lock    sync.Mutex
+}
+
+func (l *recordLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	entry := logEntry{level: level, msg: msg, fields: make(map[string]interface{})}
+	for _, field := range fields {
+		entry.fields[field.Key] = field.Value
+	}
+
+	l.lock.Lock()
+	l.entries = append(l.entries, entry)
+	l.lock.Unlock()
+}
+
+func (l *recordLogger) find(msg, method string) *logEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for i := range l.entries {
+		if l.entries[i].msg == msg && l.entries[i].fields["method"] == method {
+			return &l.entries[i]
+		}
+	}
+
+	return nil
+}
+
+func TestClientLogRedaction(t *testing.T) {
+	addr := serveOnce(t, func(req *request) interface{} {
+		switch req.Method {
+		case "server.version":
+			return []string{"ElectrumX 1.16.0", "1.4"}
+		case "blockchain.transaction.broadcast":
+			return "f3e1bf48975b8d6060a9de8884296abb80be618dc00ae3cb2f6cee3085e09403"
+		}
+		return "banner"
+	})
+
+	logger := &recordLogger{}
+	client, err := NewClient(context.Background(), addr, WithLogger(logger),
+		WithLogRedaction(RedactTransactions))
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	_, err = client.BroadcastTransaction(context.Background(), "0100000001")
+	require.NoError(t, err)
+	_, err = client.ServerBanner(context.Background())
+	require.NoError(t, err)
+
+	sent := logger.find("request sent", "blockchain.transaction.broadcast")
+	require.NotNil(t, sent)
+	assert.Equal(t, redacted, sent.fields["params"])
+	assert.Equal(t, addr, sent.fields["server"])
+	assert.Contains(t, sent.fields, "size")
+
+	received := logger.find("response received", "blockchain.transaction.broadcast")
+	require.NotNil(t, received)
+	assert.Equal(t, redacted, received.fields["payload"])
+	assert.Contains(t, received.fields, "latency")
+
+	banner := logger.find("response received", "server.banner")
+	require.NotNil(t, banner)
+	assert.Contains(t, banner.fields["payload"], `"banner"`)
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LogInfo)
+
+	logger.Log(LogDebug, "hidden")
+	logger.Log(LogInfo, "reconnected", LogField{"attempt", 2})
+
+	assert.Equal(t, "INFO reconnected attempt=2\n", buf.String())
+}
+
+func TestDebugModeLogger(t *testing.T) {
+	c := &Client{}
+	assert.Nil(t, c.getLogger())
+
+	DebugMode = true
+	defer func() { DebugMode = false }()
+
+	// Every client without logger shares the same standard logger.
+	require.NotNil(t, c.getLogger())
+	assert.Same(t, c.getLogger(), (&Client{}).getLogger())
+}
@@ -0,0 +1,212 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrInvalidMerkleProof throws an error if a merkle proof does not lead to the expected root.
+	ErrInvalidMerkleProof = errors.New("invalid merkle proof")
+
+	// ErrTxHashMismatch throws an error if a transaction returned by the remote server
+	// does not hash to the requested transaction hash.
+	ErrTxHashMismatch = errors.New("transaction does not match requested hash")
+
+	// ErrAmbiguousTxSize throws an error for 64 bytes transactions, which can be confused
+	// with an inner node of a merkle tree.
+	ErrAmbiguousTxSize = errors.New("64 bytes transactions cannot be proven with a merkle branch")
+)
+
+// MerkleProofError describes a merkle proof leading to another root than the block header one.
+type MerkleProofError struct {
+	TxHash   string
+	Height   uint32
+	Expected chainhash.Hash
+	Computed chainhash.Hash
+}
+
+func (e *MerkleProofError) Error() string {
+	return fmt.Sprintf("%v: transaction %s at height %d, computed root %s, header root %s",
+		ErrInvalidMerkleProof, e.TxHash, e.Height, e.Computed, e.Expected)
+}
+
+// Is allows matching a MerkleProofError with ErrInvalidMerkleProof.
+func (e *MerkleProofError) Is(target error) bool {
+	return target == ErrInvalidMerkleProof
+}
+
+// merkleBranchRoot folds the hex encoded branch over leaf for the leaf at index,
+// the way block transactions and Electrum header checkpoints are hashed.
+func merkleBranchRoot(leaf chainhash.Hash, branch []string, index uint32) (chainhash.Hash, error) {
+	if len(branch) < 32 && index>>uint(len(branch)) != 0 {
+		return chainhash.Hash{}, ErrInvalidMerkleProof
+	}
+
+	root := leaf
+	buf := make([]byte, 2*chainhash.HashSize)
+	for _, h := range branch {
+		node, err := chainhash.NewHashFromStr(h)
+		if err != nil {
+			return chainhash.Hash{}, err
+		}
+
+		if index&1 == 1 {
+			copy(buf, node[:])
+			copy(buf[chainhash.HashSize:], root[:])
+		} else {
+			copy(buf, root[:])
+			copy(buf[chainhash.HashSize:], node[:])
+		}
+
+		root = chainhash.DoubleHashH(buf)
+		index >>= 1
+	}
+
+	return root, nil
+}
+
+// MerkleBranch computes the merkle root of leaves and the hex encoded branch proving the
+// inclusion of the leaf at index, in the format returned by GetMerkleProof().
+func MerkleBranch(leaves []chainhash.Hash, index uint32) ([]string, chainhash.Hash) {
+	if len(leaves) == 0 {
+		return nil, chainhash.Hash{}
+	}
+
+	var branch []string
+	level := append([]chainhash.Hash(nil), leaves...)
+	buf := make([]byte, 2*chainhash.HashSize)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		branch = append(branch, level[index^1].String())
+
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			copy(buf, level[2*i][:])
+			copy(buf[chainhash.HashSize:], level[2*i+1][:])
+			next[i] = chainhash.DoubleHashH(buf)
+		}
+
+		level = next
+		index >>= 1
+	}
+
+	return branch, level[0]
+}
+
+// VerifyMerkleProof checks the merkle proof returned by GetMerkleProof() proves the
+// inclusion of the transaction txid in the block described by header.
+func VerifyMerkleProof(txid string, proof *GetMerkleProofResult, header *wire.BlockHeader) error {
+	leaf, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return err
+	}
+
+	root, err := merkleBranchRoot(*leaf, proof.Merkle, proof.Position)
+	if err != nil {
+		return err
+	}
+
+	if root != header.MerkleRoot {
+		return &MerkleProofError{
+			TxHash:   txid,
+			Height:   proof.Height,
+			Expected: header.MerkleRoot,
+			Computed: root,
+		}
+	}
+
+	return nil
+}
+
+// VerifiedTransaction is a transaction proven to be included in a block.
+type VerifiedTransaction struct {
+	Tx       *wire.MsgTx
+	Raw      string
+	Height   uint32
+	Position uint32
+	Header   *wire.BlockHeader
+}
+
+// GetVerifiedTransaction fetches a confirmed transaction, its merkle branch and the header of
+// the block at height, and proves the transaction is included in that block. The header is
+// returned as sent by the remote server, see HeaderSync.GetVerifiedTransaction() to check it
+// against a validated header chain.
+func (s *Client) GetVerifiedTransaction(ctx context.Context, txHash string, height uint32) (*VerifiedTransaction, error) {
+	result, err := s.GetBlockHeader(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := ParseBlockHeader(result.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.verifyTransaction(ctx, txHash, height, header)
+}
+
+func (s *Client) verifyTransaction(ctx context.Context, txHash string, height uint32, header *wire.BlockHeader) (*VerifiedTransaction, error) {
+	rawTx, err := s.GetRawTransaction(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 64 {
+		return nil, ErrAmbiguousTxSize
+	}
+
+	tx := &wire.MsgTx{}
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	if tx.TxHash().String() != txHash {
+		return nil, ErrTxHashMismatch
+	}
+
+	proof, err := s.GetMerkleProof(ctx, txHash, height)
+	if err != nil {
+		return nil, err
+	}
+	if proof.Height != height {
+		return nil, &MerkleProofError{TxHash: txHash, Height: proof.Height, Expected: header.MerkleRoot}
+	}
+
+	err = VerifyMerkleProof(txHash, proof, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VerifiedTransaction{
+		Tx:       tx,
+		Raw:      rawTx,
+		Height:   height,
+		Position: proof.Position,
+		Header:   header,
+	}, nil
+}
+
+// GetVerifiedTransaction fetches a confirmed transaction and its merkle branch, and proves
+// the transaction is included in the block at height of the local validated header chain.
+func (h *HeaderSync) GetVerifiedTransaction(ctx context.Context, txHash string, height uint32) (*VerifiedTransaction, error) {
+	header, err := h.store.Header(int32(height))
+	if err != nil {
+		return nil, err
+	}
+
+	return h.client.verifyTransaction(ctx, txHash, height, header)
+}
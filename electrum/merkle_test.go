@@ -0,0 +1,62 @@
+package electrum
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyMerkleProof(t *testing.T) {
+	var txs []*btcutil.Tx
+	var leaves []chainhash.Hash
+	for i := 0; i < 5; i++ {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.LockTime = uint32(i)
+		txs = append(txs, btcutil.NewTx(tx))
+		leaves = append(leaves, tx.TxHash())
+	}
+
+	merkles := blockchain.BuildMerkleTreeStore(txs, false)
+	header := &wire.BlockHeader{MerkleRoot: *merkles[len(merkles)-1]}
+
+	for i, leaf := range leaves {
+		branch, root := MerkleBranch(leaves, uint32(i))
+		assert.Equal(t, header.MerkleRoot, root)
+
+		proof := &GetMerkleProofResult{Merkle: branch, Height: 100, Position: uint32(i)}
+		require.NoError(t, VerifyMerkleProof(leaf.String(), proof, header))
+	}
+
+	branch, _ := MerkleBranch(leaves, 3)
+	proof := &GetMerkleProofResult{Merkle: branch, Height: 100, Position: 2}
+	err := VerifyMerkleProof(leaves[3].String(), proof, header)
+	assert.ErrorIs(t, err, ErrInvalidMerkleProof)
+
+	var proofErr *MerkleProofError
+	require.ErrorAs(t, err, &proofErr)
+	assert.Equal(t, uint32(100), proofErr.Height)
+}
+
+func TestVerifyHeaderProof(t *testing.T) {
+	var headers []*wire.BlockHeader
+	var leaves []chainhash.Hash
+	for i := 0; i < 7; i++ {
+		header := &wire.BlockHeader{Nonce: uint32(i)}
+		if i > 0 {
+			header.PrevBlock = leaves[i-1]
+		}
+		headers = append(headers, header)
+		leaves = append(leaves, header.BlockHash())
+	}
+
+	branch, root := MerkleBranch(leaves, 4)
+	require.NoError(t, VerifyHeaderProof(headers[4], 4, branch, root.String()))
+
+	err := VerifyHeaderProof(headers[3], 4, branch, root.String())
+	assert.ErrorIs(t, err, ErrCheckpointMismatch)
+}
@@ -31,6 +31,10 @@ func (s *Client) GetFee(ctx context.Context, target uint32) (float32, error) {
 func (s *Client) GetRelayFee(ctx context.Context) (float32, error) {
 	var resp GetFeeResp
 
+	if err := s.checkFeature("blockchain.relayfee"); err != nil {
+		return -1, err
+	}
+
 	err := s.request(ctx, "blockchain.relayfee", []interface{}{}, &resp)
 	if err != nil {
 		return -1, err
@@ -39,6 +43,45 @@ func (s *Client) GetRelayFee(ctx context.Context) (float32, error) {
 	return resp.Result, err
 }
 
+type getFeeRateResp struct {
+	Result float64 `json:"result"`
+}
+
+// GetFeeRate returns the estimated fee rate for a transaction to be confirmed within a
+// target number of blocks. ErrFeeUnavailable is returned if the remote server has no estimate.
+// https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-estimatefee
+func (s *Client) GetFeeRate(ctx context.Context, target uint32) (FeeRate, error) {
+	var resp getFeeRateResp
+
+	err := s.request(ctx, "blockchain.estimatefee", []interface{}{target}, &resp)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Result < 0 {
+		return 0, ErrFeeUnavailable
+	}
+
+	return FeeRateFromBTCPerKB(resp.Result)
+}
+
+// GetRelayFeeRate returns the minimum fee rate a transaction must pay to be accepted into the
+// remote server memory pool.
+// https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-relayfee
+func (s *Client) GetRelayFeeRate(ctx context.Context) (FeeRate, error) {
+	var resp getFeeRateResp
+
+	if err := s.checkFeature("blockchain.relayfee"); err != nil {
+		return 0, err
+	}
+
+	err := s.request(ctx, "blockchain.relayfee", []interface{}{}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return FeeRateFromBTCPerKB(resp.Result)
+}
+
 // GetFeeHistogramResp represents the response to GetFee().
 type getFeeHistogramResp struct {
 	Result [][2]uint64 `json:"result"`
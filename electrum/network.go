@@ -6,23 +6,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
 )
 
 const (
 	// ClientVersion identifies the client version/name to the remote server
 	ClientVersion = "go-electrum1.1"
 
-	// ProtocolVersion identifies the support protocol version to the remote server
+	// ProtocolVersion identifies the highest supported protocol version to the remote server
 	ProtocolVersion = "1.4"
 
 	nl = byte('\n')
 )
 
 var (
-	// DebugMode provides debug output on communications with the remote server if enabled.
+	// DebugMode provides debug output on communications with the remote server if enabled,
+	// for the clients without logger.
+	//
+	// Deprecated: use WithLogger() instead.
 	DebugMode bool
 
 	// ErrServerConnected throws an error if remote server is already connected.
@@ -56,7 +61,38 @@ type container struct {
 
 // Client stores information about the remote server.
 type Client struct {
-	transport Transport
+	transport     Transport
+	transportLock sync.RWMutex
+
+	dialer    TransportDialer
+	reconnect *ReconnectConfig
+
+	resubscribers     []resubscriber
+	resubscribersLock sync.Mutex
+	nextResubscriber  uint64
+
+	params     *chaincfg.Params
+	paramsLock sync.RWMutex
+
+	logger             Logger
+	redaction          Redaction
+	hooks              Hooks
+	interceptor        Interceptor
+	requestTimeout     time.Duration
+	notificationBuffer int
+	clientName         string
+	protocolMin        string
+	protocolMax        string
+
+	serverVersion     string
+	negotiatedVersion string
+	versionLock       sync.RWMutex
+
+	checkpoints     []Checkpoint
+	checkpointsLock sync.RWMutex
+	// defaultCheckpoints is set while checkpoints are the defaults of the network rather
+	// than the ones set with SetCheckpoints().
+	defaultCheckpoints bool
 
 	handlers     map[uint64]chan *container
 	handlersLock sync.RWMutex
@@ -64,67 +100,71 @@ type Client struct {
 	pushHandlers     map[string][]chan *container
 	pushHandlersLock sync.RWMutex
 
+	// Error receives the connection errors of the client. It holds the latest error until
+	// it is read, an older unread error being replaced by the newer one.
 	Error chan error
 	quit  chan struct{}
 
+	errorWatchers     []chan error
+	errorWatchersLock sync.Mutex
+
 	nextID uint64
+
+	lastReceived int64
+	latency      int64
 }
 
-// NewClientTCP initialize a new client for remote server and connects to the remote server using TCP
-func NewClientTCP(ctx context.Context, addr string) (*Client, error) {
-	transport, err := NewTCPTransport(ctx, addr)
+// NewClientTCP initialize a new client for remote server and connects to the remote server using TCP,
+// then negotiates the protocol version, see ServerVersion().
+func NewClientTCP(ctx context.Context, addr string, dialer ...Dialer) (*Client, error) {
+	transport, err := NewTCPTransport(ctx, addr, dialer...)
 	if err != nil {
 		return nil, err
 	}
 
-	c := &Client{
-		handlers:     make(map[uint64]chan *container),
-		pushHandlers: make(map[string][]chan *container),
-
-		Error: make(chan error),
-		quit:  make(chan struct{}),
+	c := newClient(transport, &clientOptions{})
+	err = c.start(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	c.transport = transport
-	go c.listen()
-
 	return c, nil
 }
 
-// NewClientSSL initialize a new client for remote server and connects to the remote server using SSL
-func NewClientSSL(ctx context.Context, addr string, config *tls.Config) (*Client, error) {
-	transport, err := NewSSLTransport(ctx, addr, config)
+// NewClientSSL initialize a new client for remote server and connects to the remote server using SSL,
+// then negotiates the protocol version, see ServerVersion().
+func NewClientSSL(ctx context.Context, addr string, config *tls.Config, dialer ...Dialer) (*Client, error) {
+	transport, err := NewSSLTransport(ctx, addr, config, dialer...)
 	if err != nil {
 		return nil, err
 	}
 
-	c := &Client{
-		handlers:     make(map[uint64]chan *container),
-		pushHandlers: make(map[string][]chan *container),
-
-		Error: make(chan error),
-		quit:  make(chan struct{}),
+	c := newClient(transport, &clientOptions{})
+	err = c.start(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	c.transport = transport
-	go c.listen()
-
 	return c, nil
 }
 
-type apiErr struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+type response struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Error  json.RawMessage `json:"error"`
 }
 
-func (e *apiErr) Error() string {
-	return fmt.Sprintf("errNo: %d, errMsg: %s", e.Code, e.Message)
+func (s *Client) getTransport() Transport {
+	s.transportLock.RLock()
+	defer s.transportLock.RUnlock()
+
+	return s.transport
 }
 
-type response struct {
-	ID     uint64 `json:"id"`
-	Method string `json:"method"`
-	Error  string `json:"error"`
+func (s *Client) setTransport(transport Transport) {
+	s.transportLock.Lock()
+	s.transport = transport
+	s.transportLock.Unlock()
 }
 
 func (s *Client) listen() {
@@ -132,58 +172,150 @@ func (s *Client) listen() {
 		if s.IsShutdown() {
 			break
 		}
-		if s.transport == nil {
+		transport := s.getTransport()
+		if transport == nil {
 			break
 		}
 		select {
 		case <-s.quit:
 			return
-		case err := <-s.transport.Errors():
-			s.Error <- err
-			s.Shutdown()
-		case bytes := <-s.transport.Responses():
-			result := &container{
-				content: bytes,
+		case err := <-transport.Errors():
+			if s.reconnect != nil && s.dialer != nil {
+				if s.redial(transport, err) {
+					continue
+				}
+			} else {
+				s.failPending(err)
+				// The transport closed by Shutdown() is not a connection loss.
+				if !s.IsShutdown() {
+					s.notifyError(err)
+				}
 			}
+			s.Shutdown()
+		case bytes := <-transport.Responses():
+			atomic.StoreInt64(&s.lastReceived, time.Now().UnixNano())
 
-			msg := &response{}
-			err := json.Unmarshal(bytes, msg)
-			if err != nil {
-				if DebugMode {
-					log.Printf("Unmarshal received message failed: %v", err)
-				}
-				result.err = fmt.Errorf("Unmarshal received message failed: %v", err)
-			} else if msg.Error != "" {
-				result.err = errors.New(msg.Error)
+			if batch := bytesTrimLeft(bytes); len(batch) > 0 && batch[0] == '[' {
+				s.dispatchBatch(batch)
+				continue
 			}
 
-			if len(msg.Method) > 0 {
-				s.pushHandlersLock.RLock()
-				handlers := s.pushHandlers[msg.Method]
-				s.pushHandlersLock.RUnlock()
+			s.dispatch(bytes)
+		}
+	}
+}
 
-				for _, handler := range handlers {
-					select {
-					case handler <- result:
-					default:
-					}
-				}
-			}
+// dispatchBatch splits a JSON-RPC batch response and dispatches every response it holds.
+func (s *Client) dispatchBatch(bytes []byte) {
+	var msgs []json.RawMessage
+
+	err := json.Unmarshal(bytes, &msgs)
+	if err != nil {
+		s.log(LogWarn, "unmarshal received batch failed", LogField{"size", len(bytes)}, LogField{"error", err})
+		return
+	}
+
+	for _, msg := range msgs {
+		s.dispatch(msg)
+	}
+}
+
+// dispatch routes a single received message to the request or subscriptions waiting for it.
+func (s *Client) dispatch(bytes []byte) {
+	result := &container{
+		content: bytes,
+	}
+
+	msg := &response{}
+	err := json.Unmarshal(bytes, msg)
+	if err != nil {
+		s.log(LogWarn, "unmarshal received message failed", LogField{"size", len(bytes)}, LogField{"error", err})
+		result.err = fmt.Errorf("Unmarshal received message failed: %v", err)
+	} else {
+		result.err = decodeRPCError(msg.Error)
+	}
+
+	if len(msg.Method) > 0 {
+		if s.getLogger() != nil {
+			s.log(LogDebug, "notification received", LogField{"method", msg.Method},
+				LogField{"size", len(bytes)}, s.payloadField("payload", msg.Method, bytes))
+		}
 
-			s.handlersLock.RLock()
-			c, ok := s.handlers[msg.ID]
-			s.handlersLock.RUnlock()
+		s.pushHandlersLock.RLock()
+		handlers := s.pushHandlers[msg.Method]
+		s.pushHandlersLock.RUnlock()
 
-			if ok {
-				// TODO: very rare case. fix this memory leak, when nobody will read channel (in case of error)
-				c <- result
+		var notif *NotificationInfo
+		if s.hooks != nil {
+			notif = &NotificationInfo{Method: msg.Method, Server: s.serverAddr(), Size: len(bytes)}
+			s.hooks.OnNotification(notif)
+		}
+
+		for _, handler := range handlers {
+			select {
+			case handler <- result:
+			default:
+				s.notificationDropped(msg.Method, notif)
 			}
 		}
 	}
+
+	// The ids sent start at 1, a response with a missing or null id reports a message the
+	// remote server could not read, such as a batch failing to parse as a whole. Which
+	// call it answers is unknown, so every pending call is failed with it.
+	if len(msg.Method) == 0 && msg.ID == 0 && result.err != nil {
+		s.handlersLock.RLock()
+		for _, c := range s.handlers {
+			select {
+			case c <- &container{content: bytes, err: decodeRPCError(msg.Error)}:
+			default:
+			}
+		}
+		s.handlersLock.RUnlock()
+		return
+	}
+
+	s.handlersLock.RLock()
+	c, ok := s.handlers[msg.ID]
+	s.handlersLock.RUnlock()
+
+	if ok {
+		// TODO: very rare case. fix this memory leak, when nobody will read channel (in case of error)
+		c <- result
+	}
+}
+
+// notificationDropped reports a notification discarded because the channel of its
+// subscription is full, notif being nil when unknown.
+func (s *Client) notificationDropped(method string, notif *NotificationInfo) {
+	s.log(LogWarn, "notification dropped", LogField{"method", method})
+	if s.hooks != nil {
+		if notif == nil {
+			notif = &NotificationInfo{Method: method, Server: s.serverAddr()}
+		}
+		s.hooks.OnNotificationDropped(notif)
+	}
+}
+
+// notifBuffer returns the number of notifications a subscription can queue.
+func (s *Client) notifBuffer() int {
+	if s.notificationBuffer > 0 {
+		return s.notificationBuffer
+	}
+
+	return 1
+}
+
+func bytesTrimLeft(bytes []byte) []byte {
+	for len(bytes) > 0 && (bytes[0] == ' ' || bytes[0] == '\t' || bytes[0] == '\r' || bytes[0] == nl) {
+		bytes = bytes[1:]
+	}
+
+	return bytes
 }
 
 func (s *Client) listenPush(method string) <-chan *container {
-	c := make(chan *container, 1)
+	c := make(chan *container, s.notifBuffer())
 	s.pushHandlersLock.Lock()
 	s.pushHandlers[method] = append(s.pushHandlers[method], c)
 	s.pushHandlersLock.Unlock()
@@ -198,12 +330,27 @@ type request struct {
 }
 
 func (s *Client) request(ctx context.Context, method string, params []interface{}, v interface{}) error {
+	if s.interceptor != nil {
+		return s.interceptor(ctx, method, params, v, s.invoke)
+	}
+
+	return s.invoke(ctx, method, params, v)
+}
+
+// invoke sends a single call to the remote server and decodes the response into v.
+func (s *Client) invoke(ctx context.Context, method string, params []interface{}, v interface{}) (err error) {
 	select {
 	case <-s.quit:
 		return ErrServerShutdown
 	default:
 	}
 
+	if s.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
+		defer cancel()
+	}
+
 	msg := request{
 		ID:     atomic.AddUint64(&s.nextID, 1),
 		Method: method,
@@ -217,10 +364,15 @@ func (s *Client) request(ctx context.Context, method string, params []interface{
 
 	bytes = append(bytes, nl)
 
-	err = s.transport.SendMessage(bytes)
-	if err != nil {
-		s.Shutdown()
-		return err
+	var resp *container
+	ctx, info := s.requestStart(ctx, method, msg.ID, len(bytes))
+	defer func() {
+		s.requestEnd(ctx, info, resp, err)
+	}()
+
+	transport := s.getTransport()
+	if transport == nil {
+		return ErrServerShutdown
 	}
 
 	c := make(chan *container, 1)
@@ -235,15 +387,39 @@ func (s *Client) request(ctx context.Context, method string, params []interface{
 		s.handlersLock.Unlock()
 	}()
 
-	var resp *container
+	logging := s.getLogger() != nil
+	start := info.Start
+
+	err = transport.SendMessage(bytes)
+	if err != nil {
+		s.log(LogError, "request send failed", LogField{"method", method}, LogField{"id", msg.ID},
+			LogField{"error", err})
+		if s.reconnect == nil {
+			s.Shutdown()
+		}
+		return err
+	}
+	if logging {
+		s.log(LogDebug, "request sent", LogField{"method", method}, LogField{"id", msg.ID},
+			LogField{"size", len(bytes)}, s.payloadField("params", method, params))
+	}
+
 	select {
 	case resp = <-c:
 	case <-ctx.Done():
+		s.log(LogDebug, "request timed out", LogField{"method", method}, LogField{"id", msg.ID},
+			LogField{"latency", time.Since(start)})
 		return ErrTimeout
 	}
 
+	if logging {
+		s.log(LogDebug, "response received", LogField{"method", method}, LogField{"id", msg.ID},
+			LogField{"latency", time.Since(start)}, LogField{"size", len(resp.content)},
+			s.payloadField("payload", method, resp.content))
+	}
+
 	if resp.err != nil {
-		return resp.err
+		return withMethod(resp.err, method)
 	}
 
 	if v != nil {
@@ -260,12 +436,20 @@ func (s *Client) Shutdown() {
 	if !s.IsShutdown() {
 		close(s.quit)
 	}
+	s.transportLock.Lock()
 	if s.transport != nil {
 		_ = s.transport.Close()
 	}
 	s.transport = nil
-	s.handlers = nil
-	s.pushHandlers = nil
+	s.transportLock.Unlock()
+
+	s.handlersLock.Lock()
+	s.handlers = make(map[uint64]chan *container)
+	s.handlersLock.Unlock()
+
+	s.pushHandlersLock.Lock()
+	s.pushHandlers = make(map[string][]chan *container)
+	s.pushHandlersLock.Unlock()
 }
 
 func (s *Client) IsShutdown() bool {
@@ -0,0 +1,326 @@
+package electrum
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+var (
+	// ErrUnknownTransport throws an error if a transport type or address scheme is not supported.
+	ErrUnknownTransport = errors.New("unknown transport")
+)
+
+// TransportType selects how NewClient() connects to the remote server.
+type TransportType int
+
+const (
+	// TransportAuto picks the transport from the address scheme, tcp:// ssl:// tls:// ws://
+	// or wss://. Addresses without scheme use SSL when a TLS config is given, TCP otherwise.
+	TransportAuto TransportType = iota
+
+	// TransportTCP connects using plain TCP.
+	TransportTCP
+
+	// TransportSSL connects using TCP with TLS.
+	TransportSSL
+
+	// TransportWebSocket connects using WebSocket, the address being a ws:// or wss:// url.
+	TransportWebSocket
+)
+
+// Option configures a client created with NewClient().
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	transport       TransportType
+	tlsConfig       *tls.Config
+	dialer          Dialer
+	transportDialer TransportDialer
+	reconnect       *ReconnectConfig
+
+	logger             Logger
+	redaction          Redaction
+	hooks              multiHooks
+	interceptors       []Interceptor
+	requestTimeout     time.Duration
+	responseBuffer     int
+	notificationBuffer int
+	keepAlive          *KeepAliveConfig
+
+	params      *chaincfg.Params
+	clientName  string
+	protocolMin string
+	protocolMax string
+}
+
+// WithTransport selects the transport instead of guessing it from the address.
+func WithTransport(transport TransportType) Option {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithTLSConfig sets the TLS config of SSL and wss:// connections.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *clientOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithDialer opens the connections through dialer, for instance a SOCKS5 proxy.
+func WithDialer(dialer Dialer) Option {
+	return func(o *clientOptions) {
+		o.dialer = dialer
+	}
+}
+
+// WithTransportDialer opens the transports with dialer, ignoring the address and the
+// transport, TLS and dialer options.
+func WithTransportDialer(dialer TransportDialer) Option {
+	return func(o *clientOptions) {
+		o.transportDialer = dialer
+	}
+}
+
+// WithReconnect redials the remote server when the connection is lost, see NewReconnectingClient().
+// A nil config uses DefaultReconnectConfig().
+func WithReconnect(config *ReconnectConfig) Option {
+	return func(o *clientOptions) {
+		if config == nil {
+			config = DefaultReconnectConfig()
+		}
+		o.reconnect = config
+	}
+}
+
+// WithLogger writes the log entries of this client to logger, see NewStdLogger() and
+// NewSlogLogger().
+func WithLogger(logger Logger) Option {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithLogRedaction hides the payloads selected by redaction from the log entries.
+func WithLogRedaction(redaction Redaction) Option {
+	return func(o *clientOptions) {
+		o.redaction = redaction
+	}
+}
+
+// WithHooks instruments the client with hooks, called in the given order when requests start
+// and in the reverse order when they end.
+func WithHooks(hooks ...Hooks) Option {
+	return func(o *clientOptions) {
+		o.hooks = append(o.hooks, hooks...)
+	}
+}
+
+// WithInterceptors wraps every call of the client with interceptors, the first being the
+// outermost, see Interceptor.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *clientOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithRequestTimeout bounds every request, in addition to the deadline of its context.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.requestTimeout = timeout
+	}
+}
+
+// WithBufferSizes sets the number of messages received from the remote server that can be
+// queued before being dispatched, and the number of notifications each subscription can
+// queue before they are dropped.
+func WithBufferSizes(responses, notifications int) Option {
+	return func(o *clientOptions) {
+		o.responseBuffer = responses
+		o.notificationBuffer = notifications
+	}
+}
+
+// WithKeepAlive pings the remote server once the connection has been idle for interval, so
+// idle connections are not closed, with the other settings of DefaultKeepAliveConfig().
+func WithKeepAlive(interval time.Duration) Option {
+	return func(o *clientOptions) {
+		o.keepAlive = DefaultKeepAliveConfig()
+		o.keepAlive.Interval = interval
+	}
+}
+
+// WithKeepAliveConfig pings the remote server as configured, see KeepAliveConfig.
+// A nil config uses DefaultKeepAliveConfig().
+func WithKeepAliveConfig(config *KeepAliveConfig) Option {
+	return func(o *clientOptions) {
+		if config == nil {
+			config = DefaultKeepAliveConfig()
+		}
+		o.keepAlive = config
+	}
+}
+
+// WithChainParams sets the network of the client, see SetChainParams().
+func WithChainParams(params *chaincfg.Params) Option {
+	return func(o *clientOptions) {
+		o.params = params
+	}
+}
+
+// WithClientVersion sets the client name and the only protocol version sent to the remote
+// server, ClientVersion and a range up to ProtocolVersion by default.
+func WithClientVersion(clientName, protocolVersion string) Option {
+	return func(o *clientOptions) {
+		o.clientName = clientName
+		o.protocolMin = protocolVersion
+		o.protocolMax = protocolVersion
+	}
+}
+
+// WithProtocolVersions sets the range of protocol versions negotiated with the remote server,
+// from ProtocolVersionMin to ProtocolVersion by default. Versions below ProtocolVersionMin are
+// raised to it. Calls unavailable in the agreed version return ErrNotImplemented or
+// ErrDeprecated.
+func WithProtocolVersions(min, max string) Option {
+	return func(o *clientOptions) {
+		o.protocolMin = min
+		o.protocolMax = max
+	}
+}
+
+// NewClient initialize a new client for remote server and connects to the remote server at
+// addr, configured by opts, then negotiates the protocol version. Without options, it
+// connects using TCP.
+func NewClient(ctx context.Context, addr string, opts ...Option) (*Client, error) {
+	o := &clientOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	dialer := o.transportDialer
+	if dialer == nil {
+		var err error
+		dialer, err = o.newTransportDialer(addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transport, err := dialer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newClient(transport, o)
+	if o.reconnect != nil {
+		c.dialer = dialer
+		c.reconnect = o.reconnect
+	}
+
+	err = c.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if o.keepAlive != nil && o.keepAlive.Interval > 0 {
+		go c.keepAlive(o.keepAlive)
+	}
+
+	return c, nil
+}
+
+// newTransportDialer returns the TransportDialer connecting to addr as configured.
+func (o *clientOptions) newTransportDialer(addr string) (TransportDialer, error) {
+	transport := o.transport
+	if i := strings.Index(addr, "://"); i >= 0 {
+		scheme := strings.ToLower(addr[:i])
+		switch scheme {
+		case "tcp":
+			transport, addr = TransportTCP, addr[i+3:]
+		case "ssl", "tls":
+			transport, addr = TransportSSL, addr[i+3:]
+		case "ws", "wss":
+			transport = TransportWebSocket
+		default:
+			return nil, ErrUnknownTransport
+		}
+	}
+	if transport == TransportAuto {
+		transport = TransportTCP
+		if o.tlsConfig != nil {
+			transport = TransportSSL
+		}
+	}
+
+	switch transport {
+	case TransportTCP:
+		return func(ctx context.Context) (Transport, error) {
+			conn, err := dialTCP(ctx, addr, o.dialer)
+			if err != nil {
+				return nil, err
+			}
+
+			return newTCPTransport(conn, o.responseBuffer), nil
+		}, nil
+
+	case TransportSSL:
+		return func(ctx context.Context) (Transport, error) {
+			conn, err := dialSSL(ctx, addr, o.tlsConfig, o.dialer)
+			if err != nil {
+				return nil, err
+			}
+
+			return newTCPTransport(conn, o.responseBuffer), nil
+		}, nil
+
+	case TransportWebSocket:
+		return func(ctx context.Context) (Transport, error) {
+			conn, err := dialWebSocket(ctx, addr, o.tlsConfig, o.dialer)
+			if err != nil {
+				return nil, err
+			}
+
+			return newWebSocketTransport(conn, o.responseBuffer), nil
+		}, nil
+	}
+
+	return nil, ErrUnknownTransport
+}
+
+// newClient initialize a client over transport, without starting it.
+func newClient(transport Transport, o *clientOptions) *Client {
+	c := &Client{
+		transport: transport,
+
+		handlers:     make(map[uint64]chan *container),
+		pushHandlers: make(map[string][]chan *container),
+
+		logger:             o.logger,
+		redaction:          o.redaction,
+		requestTimeout:     o.requestTimeout,
+		notificationBuffer: o.notificationBuffer,
+		clientName:         o.clientName,
+		protocolMin:        o.protocolMin,
+		protocolMax:        o.protocolMax,
+
+		Error: make(chan error, 1),
+		quit:  make(chan struct{}),
+	}
+
+	if len(o.hooks) > 0 {
+		c.hooks = o.hooks
+	}
+	if len(o.interceptors) > 0 {
+		c.interceptor = ChainInterceptors(o.interceptors...)
+	}
+	if o.params != nil {
+		c.SetChainParams(o.params)
+	}
+
+	return c
+}
@@ -0,0 +1,61 @@
+package electrum
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, err := bufio.NewReader(conn).ReadBytes(nl)
+		if err != nil {
+			return
+		}
+
+		var req request
+		_ = json.Unmarshal(line, &req)
+		received <- req
+
+		_, _ = conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":["ElectrumX 1.16.0","1.4"]}` + "\n"))
+	}()
+
+	client, err := NewClient(context.Background(), "tcp://"+listener.Addr().String(),
+		WithClientVersion("test-wallet", "1.4"),
+		WithRequestTimeout(time.Second),
+		WithBufferSizes(4, 4),
+	)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	serverVer, protocolVer, err := client.ServerVersion(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ElectrumX 1.16.0", serverVer)
+	assert.Equal(t, "1.4", protocolVer)
+
+	req := <-received
+	assert.Equal(t, "server.version", req.Method)
+	assert.Equal(t, []interface{}{"test-wallet", "1.4"}, req.Params)
+}
+
+func TestNewClientUnknownScheme(t *testing.T) {
+	_, err := NewClient(context.Background(), "udp://127.0.0.1:50001")
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+}
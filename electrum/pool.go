@@ -0,0 +1,758 @@
+package electrum
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+var (
+	// ErrNoHealthyServer throws an error if no server of the pool is able to serve a request.
+	ErrNoHealthyServer = errors.New("no healthy server available in pool")
+)
+
+// PoolConfig controls the health checks and failover of a Pool.
+type PoolConfig struct {
+	// HealthCheckInterval is the delay between two pings of every server of the pool. With 0,
+	// no health check runs and the ejected servers are instead tried after the healthy ones,
+	// being admitted again as soon as they answer a request.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds a single health check ping.
+	HealthCheckTimeout time.Duration
+	// MaxAttempts is the number of servers an idempotent request is tried on, 0 means all of them.
+	MaxAttempts int
+}
+
+// DefaultPoolConfig returns the pool settings used when none are provided.
+func DefaultPoolConfig() *PoolConfig {
+	return &PoolConfig{
+		HealthCheckInterval: 30 * time.Second,
+		HealthCheckTimeout:  10 * time.Second,
+	}
+}
+
+type poolMember struct {
+	client  *Client
+	healthy int32
+}
+
+func (m *poolMember) isHealthy() bool {
+	return atomic.LoadInt32(&m.healthy) == 1 && !m.client.IsShutdown()
+}
+
+func (m *poolMember) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&m.healthy, 1)
+	} else {
+		atomic.StoreInt32(&m.healthy, 0)
+	}
+}
+
+// API is the set of methods shared by Client and Pool, so code can be written against
+// either a single server or a pool of them. The scripthash subscriptions are left out, the
+// Pool ones failing with ErrNoHealthyServer where a client always succeeds.
+type API interface {
+	Ping(ctx context.Context) error
+	ServerAddPeer(ctx context.Context, features *ServerFeaturesResult) error
+	ServerBanner(ctx context.Context) (string, error)
+	ServerDonation(ctx context.Context) (string, error)
+	ServerFeatures(ctx context.Context) (*ServerFeaturesResult, error)
+	ServerPeers(ctx context.Context) (interface{}, error)
+	ServerVersion(ctx context.Context) (serverVer, protocolVer string, err error)
+
+	GetBlockHeader(ctx context.Context, height uint32, checkpointHeight ...uint32) (*GetBlockHeaderResult, error)
+	GetBlockHeaders(ctx context.Context, startHeight, count uint32, checkpointHeight ...uint32) (*GetBlockHeadersResult, error)
+	SetCheckpoints(cps ...Checkpoint)
+
+	GetFee(ctx context.Context, target uint32) (float32, error)
+	GetRelayFee(ctx context.Context) (float32, error)
+	GetFeeRate(ctx context.Context, target uint32) (FeeRate, error)
+	GetRelayFeeRate(ctx context.Context) (FeeRate, error)
+	GetFeeHistogram(ctx context.Context) (map[uint32]uint64, error)
+
+	GetBalance(ctx context.Context, scripthash string) (GetBalanceResult, error)
+	GetHistory(ctx context.Context, scripthash string) ([]*GetMempoolResult, error)
+	GetMempool(ctx context.Context, scripthash string) ([]*GetMempoolResult, error)
+	ListUnspent(ctx context.Context, scripthash string) ([]*ListUnspentResult, error)
+
+	BroadcastTransaction(ctx context.Context, rawTx string) (string, error)
+	GetTransaction(ctx context.Context, txHash string) (*GetTransactionResult, error)
+	GetRawTransaction(ctx context.Context, txHash string) (string, error)
+	GetMerkleProof(ctx context.Context, txHash string, height uint32) (*GetMerkleProofResult, error)
+	GetHashFromPosition(ctx context.Context, height, position uint32) (string, error)
+	GetMerkleProofFromPosition(ctx context.Context, height, position uint32) (*GetMerkleProofFromPosResult, error)
+	GetVerifiedTransaction(ctx context.Context, txHash string, height uint32) (*VerifiedTransaction, error)
+
+	SetChainParams(params *chaincfg.Params)
+	ChainParams() *chaincfg.Params
+	AddressToElectrumScriptHash(address string) (string, error)
+	ScanExtendedKey(ctx context.Context, key string, gapLimit uint32) (*AccountScanResult, error)
+	ScanAccount(ctx context.Context, account *hdkeychain.ExtendedKey, addrType AddressType, gapLimit uint32) (*AccountScanResult, error)
+
+	Batch() *Batch
+	SubscribeHeaders(ctx context.Context) (<-chan *SubscribeHeadersResult, error)
+	SubscribeMasternode(ctx context.Context, collateral string) (<-chan string, error)
+
+	Shutdown()
+	IsShutdown() bool
+}
+
+var (
+	_ API = (*Client)(nil)
+	_ API = (*Pool)(nil)
+)
+
+// Pool spreads requests across several clients connected to different remote servers.
+// Servers failing their health check or reporting a connection error are ejected from the
+// rotation until they answer again, and idempotent requests failing because of a lost
+// connection or a timeout are retried on another server. Pool implements API like Client,
+// the per connection methods such as Latency() or NegotiatedVersion() being left to its
+// clients.
+type Pool struct {
+	config *PoolConfig
+
+	members     []*poolMember
+	membersLock sync.RWMutex
+
+	// params and checkpoints are applied to the clients added to the pool.
+	params      *chaincfg.Params
+	checkpoints []Checkpoint
+
+	next uint64
+	quit chan struct{}
+}
+
+// NewPool initialize a new pool over already connected clients and starts health checking them.
+func NewPool(config *PoolConfig, clients ...*Client) *Pool {
+	if config == nil {
+		config = DefaultPoolConfig()
+	}
+
+	p := &Pool{
+		config: config,
+		quit:   make(chan struct{}),
+	}
+
+	for _, c := range clients {
+		p.Add(c)
+	}
+
+	if config.HealthCheckInterval > 0 {
+		go p.healthCheck()
+	}
+
+	return p
+}
+
+// Add inserts a new client in the pool rotation. The pool follows the errors reported by
+// the client from then on, to eject it as soon as its connection fails, while leaving
+// its Error channel to the caller.
+func (p *Pool) Add(c *Client) {
+	m := &poolMember{client: c, healthy: 1}
+
+	p.membersLock.Lock()
+	if p.params != nil {
+		c.SetChainParams(p.params)
+	}
+	if p.checkpoints != nil {
+		c.SetCheckpoints(p.checkpoints...)
+	}
+	p.members = append(p.members, m)
+	p.membersLock.Unlock()
+
+	go p.watch(m, c.watchErrors())
+}
+
+// watch ejects a member each time its client reports an error, until the client or the
+// pool is shut down.
+func (p *Pool) watch(m *poolMember, errs <-chan error) {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-m.client.quit:
+			m.setHealthy(false)
+			return
+		case <-errs:
+			m.setHealthy(false)
+		}
+	}
+}
+
+// Clients returns the clients currently considered healthy.
+func (p *Pool) Clients() []*Client {
+	p.membersLock.RLock()
+	defer p.membersLock.RUnlock()
+
+	var clients []*Client
+	for _, m := range p.members {
+		if m.isHealthy() {
+			clients = append(clients, m.client)
+		}
+	}
+
+	return clients
+}
+
+// Shutdown stops health checking and shuts down every client of the pool.
+func (p *Pool) Shutdown() {
+	if !p.IsShutdown() {
+		close(p.quit)
+	}
+
+	p.membersLock.Lock()
+	for _, m := range p.members {
+		m.client.Shutdown()
+	}
+	p.members = nil
+	p.membersLock.Unlock()
+}
+
+// IsShutdown returns true once the pool has been shut down.
+func (p *Pool) IsShutdown() bool {
+	select {
+	case <-p.quit:
+		return true
+	default:
+	}
+	return false
+}
+
+func (p *Pool) healthCheck() {
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+			p.checkMembers()
+		}
+	}
+}
+
+func (p *Pool) checkMembers() {
+	p.membersLock.Lock()
+	members := p.members[:0]
+	for _, m := range p.members {
+		if !m.client.IsShutdown() {
+			members = append(members, m)
+		}
+	}
+	p.members = members
+	p.membersLock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, m := range members {
+		wg.Add(1)
+		go func(m *poolMember) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), p.config.HealthCheckTimeout)
+			defer cancel()
+
+			m.setHealthy(m.client.Ping(ctx) == nil)
+		}(m)
+	}
+	wg.Wait()
+}
+
+// candidates returns the healthy members, rotated for round-robin balancing. Without
+// health checks, the ejected members still connected follow them.
+func (p *Pool) candidates() []*poolMember {
+	p.membersLock.RLock()
+	defer p.membersLock.RUnlock()
+
+	var healthy, ejected []*poolMember
+	for _, m := range p.members {
+		if m.isHealthy() {
+			healthy = append(healthy, m)
+		} else if p.config.HealthCheckInterval <= 0 && !m.client.IsShutdown() {
+			ejected = append(ejected, m)
+		}
+	}
+	if len(healthy) == 0 {
+		return ejected
+	}
+
+	start := int(atomic.AddUint64(&p.next, 1) % uint64(len(healthy)))
+
+	rotated := make([]*poolMember, 0, len(healthy)+len(ejected))
+	rotated = append(rotated, healthy[start:]...)
+	rotated = append(rotated, healthy[:start]...)
+
+	return append(rotated, ejected...)
+}
+
+// primary returns the client subscriptions are bound to.
+func (p *Pool) primary() *Client {
+	candidates := p.candidates()
+	if len(candidates) > 0 {
+		return candidates[0].client
+	}
+
+	p.membersLock.RLock()
+	defer p.membersLock.RUnlock()
+
+	if len(p.members) > 0 {
+		return p.members[0].client
+	}
+
+	return nil
+}
+
+// do runs fn against a healthy client. Idempotent calls failing because of the
+// connection, or timing out while ctx is not done, are transparently retried on the
+// next healthy client.
+func (p *Pool) do(ctx context.Context, idempotent bool, fn func(*Client) error) error {
+	if p.IsShutdown() {
+		return ErrServerShutdown
+	}
+
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return ErrNoHealthyServer
+	}
+
+	attempts := len(candidates)
+	if !idempotent {
+		attempts = 1
+	} else if p.config.MaxAttempts > 0 && p.config.MaxAttempts < attempts {
+		attempts = p.config.MaxAttempts
+	}
+
+	var err error
+	for _, m := range candidates[:attempts] {
+		err = fn(m.client)
+		if err == nil {
+			m.setHealthy(true)
+			return nil
+		}
+		if ctx.Err() != nil || !(isConnectionError(err) || errors.Is(err, ErrTimeout)) {
+			return err
+		}
+
+		m.setHealthy(false)
+	}
+
+	return err
+}
+
+// isConnectionError reports whether err comes from the connection to the remote
+// server rather than from the server answering the request.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, ErrServerShutdown) || errors.Is(err, ErrReconnectFailed) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) || errors.As(err, &netErr)
+}
+
+// Ping sends a ping to a server of the pool.
+func (p *Pool) Ping(ctx context.Context) error {
+	return p.do(ctx, true, func(c *Client) error {
+		return c.Ping(ctx)
+	})
+}
+
+// ServerAddPeer adds your new server into the peers list of a server of the pool.
+func (p *Pool) ServerAddPeer(ctx context.Context, features *ServerFeaturesResult) error {
+	return p.do(ctx, false, func(c *Client) error {
+		return c.ServerAddPeer(ctx, features)
+	})
+}
+
+// ServerBanner returns the banner of a server of the pool.
+func (p *Pool) ServerBanner(ctx context.Context) (banner string, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		banner, err = c.ServerBanner(ctx)
+		return
+	})
+
+	return
+}
+
+// ServerDonation returns the donation address of a server of the pool.
+func (p *Pool) ServerDonation(ctx context.Context) (address string, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		address, err = c.ServerDonation(ctx)
+		return
+	})
+
+	return
+}
+
+// ServerFeatures returns the features of a server of the pool.
+func (p *Pool) ServerFeatures(ctx context.Context) (features *ServerFeaturesResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		features, err = c.ServerFeatures(ctx)
+		return
+	})
+
+	return
+}
+
+// ServerPeers returns the peers known by a server of the pool.
+func (p *Pool) ServerPeers(ctx context.Context) (peers interface{}, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		peers, err = c.ServerPeers(ctx)
+		return
+	})
+
+	return
+}
+
+// ServerVersion negotiates the protocol version with every server of the pool and
+// returns the answer of the first one. Servers failing the negotiation are ejected.
+func (p *Pool) ServerVersion(ctx context.Context) (serverVer, protocolVer string, err error) {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return "", "", ErrNoHealthyServer
+	}
+
+	var negotiated bool
+	for _, m := range candidates {
+		s, v, e := m.client.ServerVersion(ctx)
+		if e != nil {
+			m.setHealthy(false)
+			err = e
+			continue
+		}
+
+		if !negotiated {
+			serverVer, protocolVer, negotiated = s, v, true
+		}
+	}
+
+	if negotiated {
+		err = nil
+	}
+
+	return
+}
+
+// GetBlockHeader returns the block header at a specific height from a server of the pool.
+func (p *Pool) GetBlockHeader(ctx context.Context, height uint32, checkpointHeight ...uint32) (header *GetBlockHeaderResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		header, err = c.GetBlockHeader(ctx, height, checkpointHeight...)
+		return
+	})
+
+	return
+}
+
+// GetBlockHeaders return a concatenated chunk of block headers from a server of the pool.
+func (p *Pool) GetBlockHeaders(ctx context.Context, startHeight, count uint32,
+	checkpointHeight ...uint32) (headers *GetBlockHeadersResult, err error) {
+
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		headers, err = c.GetBlockHeaders(ctx, startHeight, count, checkpointHeight...)
+		return
+	})
+
+	return
+}
+
+// GetFee returns the estimated transaction fee per kilobytes from a server of the pool.
+func (p *Pool) GetFee(ctx context.Context, target uint32) (fee float32, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		fee, err = c.GetFee(ctx, target)
+		return
+	})
+
+	return
+}
+
+// GetRelayFee returns the minimum relay fee of a server of the pool.
+func (p *Pool) GetRelayFee(ctx context.Context) (fee float32, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		fee, err = c.GetRelayFee(ctx)
+		return
+	})
+
+	return
+}
+
+// GetFeeRate returns the estimated fee rate from a server of the pool.
+func (p *Pool) GetFeeRate(ctx context.Context, target uint32) (rate FeeRate, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		rate, err = c.GetFeeRate(ctx, target)
+		return
+	})
+
+	return
+}
+
+// GetRelayFeeRate returns the minimum relay fee rate of a server of the pool.
+func (p *Pool) GetRelayFeeRate(ctx context.Context) (rate FeeRate, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		rate, err = c.GetRelayFeeRate(ctx)
+		return
+	})
+
+	return
+}
+
+// GetFeeHistogram returns the memory pool fee histogram of a server of the pool.
+func (p *Pool) GetFeeHistogram(ctx context.Context) (histogram map[uint32]uint64, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		histogram, err = c.GetFeeHistogram(ctx)
+		return
+	})
+
+	return
+}
+
+// GetBalance returns the confirmed and unconfirmed balance for a scripthash.
+func (p *Pool) GetBalance(ctx context.Context, scripthash string) (balance GetBalanceResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		balance, err = c.GetBalance(ctx, scripthash)
+		return
+	})
+
+	return
+}
+
+// GetHistory returns the confirmed and unconfirmed history for a scripthash.
+func (p *Pool) GetHistory(ctx context.Context, scripthash string) (history []*GetMempoolResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		history, err = c.GetHistory(ctx, scripthash)
+		return
+	})
+
+	return
+}
+
+// GetMempool returns the unconfirmed transacations of a scripthash.
+func (p *Pool) GetMempool(ctx context.Context, scripthash string) (mempool []*GetMempoolResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		mempool, err = c.GetMempool(ctx, scripthash)
+		return
+	})
+
+	return
+}
+
+// ListUnspent returns an ordered list of UTXOs for a scripthash.
+func (p *Pool) ListUnspent(ctx context.Context, scripthash string) (unspent []*ListUnspentResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		unspent, err = c.ListUnspent(ctx, scripthash)
+		return
+	})
+
+	return
+}
+
+// BroadcastTransaction sends a raw transaction to a server of the pool. It is never
+// retried on another server, as the first one may already have relayed it.
+func (p *Pool) BroadcastTransaction(ctx context.Context, rawTx string) (txid string, err error) {
+	err = p.do(ctx, false, func(c *Client) (err error) {
+		txid, err = c.BroadcastTransaction(ctx, rawTx)
+		return
+	})
+
+	return
+}
+
+// GetTransaction gets the detailed information for a transaction.
+func (p *Pool) GetTransaction(ctx context.Context, txHash string) (tx *GetTransactionResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		tx, err = c.GetTransaction(ctx, txHash)
+		return
+	})
+
+	return
+}
+
+// GetRawTransaction gets a raw encoded transaction.
+func (p *Pool) GetRawTransaction(ctx context.Context, txHash string) (rawTx string, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		rawTx, err = c.GetRawTransaction(ctx, txHash)
+		return
+	})
+
+	return
+}
+
+// GetMerkleProof returns the merkle proof for a confirmed transaction.
+func (p *Pool) GetMerkleProof(ctx context.Context, txHash string, height uint32) (proof *GetMerkleProofResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		proof, err = c.GetMerkleProof(ctx, txHash, height)
+		return
+	})
+
+	return
+}
+
+// GetHashFromPosition returns the transaction hash for a specific position in a block.
+func (p *Pool) GetHashFromPosition(ctx context.Context, height, position uint32) (txHash string, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		txHash, err = c.GetHashFromPosition(ctx, height, position)
+		return
+	})
+
+	return
+}
+
+// GetMerkleProofFromPosition returns the merkle proof for a specific position in a block.
+func (p *Pool) GetMerkleProofFromPosition(ctx context.Context, height, position uint32) (proof *GetMerkleProofFromPosResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		proof, err = c.GetMerkleProofFromPosition(ctx, height, position)
+		return
+	})
+
+	return
+}
+
+// GetVerifiedTransaction returns a confirmed transaction along with its verified merkle proof
+// from a server of the pool.
+func (p *Pool) GetVerifiedTransaction(ctx context.Context, txHash string, height uint32) (tx *VerifiedTransaction, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		tx, err = c.GetVerifiedTransaction(ctx, txHash, height)
+		return
+	})
+
+	return
+}
+
+// SetCheckpoints replaces the trusted checkpoints of every client of the pool, including
+// the clients added later.
+func (p *Pool) SetCheckpoints(cps ...Checkpoint) {
+	p.membersLock.Lock()
+	defer p.membersLock.Unlock()
+
+	p.checkpoints = append([]Checkpoint{}, cps...)
+	for _, m := range p.members {
+		m.client.SetCheckpoints(cps...)
+	}
+}
+
+// SetChainParams sets the network of every client of the pool, including the clients
+// added later.
+func (p *Pool) SetChainParams(params *chaincfg.Params) {
+	p.membersLock.Lock()
+	defer p.membersLock.Unlock()
+
+	p.params = params
+	for _, m := range p.members {
+		m.client.SetChainParams(params)
+	}
+}
+
+// ChainParams returns the network of the pool, the bitcoin main network by default.
+func (p *Pool) ChainParams() *chaincfg.Params {
+	p.membersLock.RLock()
+	defer p.membersLock.RUnlock()
+
+	if p.params == nil {
+		return &chaincfg.MainNetParams
+	}
+
+	return p.params
+}
+
+// AddressToElectrumScriptHash converts an address of the pool network to electrum scriptHash.
+func (p *Pool) AddressToElectrumScriptHash(address string) (string, error) {
+	return AddressToElectrumScriptHash(address, p.ChainParams())
+}
+
+// ScanExtendedKey scans the account of the extended public key on a server of the pool.
+// See Client.ScanExtendedKey().
+func (p *Pool) ScanExtendedKey(ctx context.Context, key string, gapLimit uint32) (result *AccountScanResult, err error) {
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		result, err = c.ScanExtendedKey(ctx, key, gapLimit)
+		return
+	})
+
+	return
+}
+
+// ScanAccount scans the addresses of an account on a server of the pool. See Client.ScanAccount().
+func (p *Pool) ScanAccount(ctx context.Context, account *hdkeychain.ExtendedKey, addrType AddressType,
+	gapLimit uint32) (result *AccountScanResult, err error) {
+
+	err = p.do(ctx, true, func(c *Client) (err error) {
+		result, err = c.ScanAccount(ctx, account, addrType, gapLimit)
+		return
+	})
+
+	return
+}
+
+// Batch returns a new empty batch of calls bound to a healthy server of the pool. The batch
+// is not failed over, and fails with ErrNoHealthyServer when the pool has no server.
+func (p *Pool) Batch() *Batch {
+	return &Batch{client: p.primary()}
+}
+
+// SubscribeHeaders subscribes to block headers notifications on a healthy server of the pool.
+// Notifications are bound to that server connection and are not failed over.
+func (p *Pool) SubscribeHeaders(ctx context.Context) (<-chan *SubscribeHeadersResult, error) {
+	c := p.primary()
+	if c == nil {
+		return nil, ErrNoHealthyServer
+	}
+
+	return c.SubscribeHeaders(ctx)
+}
+
+// SubscribeScripthash creates a scripthash subscription on a healthy server of the pool.
+// Notifications are bound to that server connection and are not failed over.
+// It fails with ErrNoHealthyServer when the pool has no server.
+func (p *Pool) SubscribeScripthash() (*ScripthashSubscription, <-chan *SubscribeNotif, error) {
+	c := p.primary()
+	if c == nil {
+		return nil, nil, ErrNoHealthyServer
+	}
+
+	sub, notifs := c.SubscribeScripthash()
+
+	return sub, notifs, nil
+}
+
+// SubscribeMasternode subscribes to masternode status notifications on a healthy server of the pool.
+// Notifications are bound to that server connection and are not failed over.
+func (p *Pool) SubscribeMasternode(ctx context.Context, collateral string) (<-chan string, error) {
+	c := p.primary()
+	if c == nil {
+		return nil, ErrNoHealthyServer
+	}
+
+	return c.SubscribeMasternode(ctx, collateral)
+}
+
+// SubscribeScripthashVerified creates a verified scripthash subscription on a healthy server
+// of the pool. Notifications are bound to that server connection and are not failed over.
+// It fails with ErrNoHealthyServer when the pool has no server.
+func (p *Pool) SubscribeScripthashVerified(ctx context.Context) (*VerifiedSubscription, <-chan *HistoryDiff, error) {
+	c := p.primary()
+	if c == nil {
+		return nil, nil, ErrNoHealthyServer
+	}
+
+	sub, notifs := c.SubscribeScripthashVerified(ctx)
+
+	return sub, notifs, nil
+}
+
+// SubscribeScripthashEvents creates a scripthash events subscription on a healthy server of
+// the pool. Notifications are bound to that server connection and are not failed over.
+// It fails with ErrNoHealthyServer when the pool has no server.
+func (p *Pool) SubscribeScripthashEvents(ctx context.Context) (*ScripthashEvents, <-chan *ScripthashEvent, error) {
+	c := p.primary()
+	if c == nil {
+		return nil, nil, ErrNoHealthyServer
+	}
+
+	sub, notifs := c.SubscribeScripthashEvents(ctx)
+
+	return sub, notifs, nil
+}
@@ -0,0 +1,194 @@
+package electrum_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPoolServers starts n servers answering server.banner with their index, and returns
+// them along with a client connected to each of them.
+func newPoolServers(t *testing.T, n int, opts ...electrum.Option) ([]*electrumtest.Server, []*electrum.Client) {
+	servers := make([]*electrumtest.Server, n)
+	clients := make([]*electrum.Client, n)
+	for i := range servers {
+		servers[i] = electrumtest.NewServer()
+		servers[i].SetResult("server.banner", string(rune('a'+i)))
+		servers[i].SetResult("blockchain.scripthash.get_balance", &electrum.GetBalanceResult{Confirmed: 1000})
+		t.Cleanup(servers[i].Close)
+
+		client, err := servers[i].NewClient(context.Background(), opts...)
+		require.NoError(t, err)
+		clients[i] = client
+	}
+
+	return servers, clients
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	_, clients := newPoolServers(t, 3)
+	pool := electrum.NewPool(&electrum.PoolConfig{}, clients...)
+	defer pool.Shutdown()
+
+	banners := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		banner, err := pool.ServerBanner(context.Background())
+		require.NoError(t, err)
+		banners[banner]++
+	}
+
+	assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, banners)
+}
+
+func TestPoolEjection(t *testing.T) {
+	servers, clients := newPoolServers(t, 2)
+	pool := electrum.NewPool(&electrum.PoolConfig{}, clients...)
+	defer pool.Shutdown()
+
+	servers[0].Close()
+
+	assert.Eventually(t, func() bool {
+		return len(pool.Clients()) == 1
+	}, time.Second, time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		banner, err := pool.ServerBanner(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "b", banner)
+	}
+
+	servers[1].Close()
+
+	assert.Eventually(t, func() bool {
+		return len(pool.Clients()) == 0
+	}, time.Second, time.Millisecond)
+
+	_, err := pool.ServerBanner(context.Background())
+	assert.ErrorIs(t, err, electrum.ErrNoHealthyServer)
+}
+
+func TestPoolHealthCheckReadmission(t *testing.T) {
+	servers, clients := newPoolServers(t, 2)
+	pool := electrum.NewPool(&electrum.PoolConfig{
+		HealthCheckInterval: 20 * time.Millisecond,
+		HealthCheckTimeout:  10 * time.Millisecond,
+	}, clients...)
+	defer pool.Shutdown()
+
+	servers[0].SetLatency(50 * time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return len(pool.Clients()) == 1
+	}, time.Second, time.Millisecond)
+
+	servers[0].SetLatency(0)
+	assert.Eventually(t, func() bool {
+		return len(pool.Clients()) == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestPoolLazyReadmission(t *testing.T) {
+	servers, clients := newPoolServers(t, 2, electrum.WithRequestTimeout(20*time.Millisecond))
+	pool := electrum.NewPool(&electrum.PoolConfig{}, clients...)
+	defer pool.Shutdown()
+
+	// Without health checks, the slow server is ejected by its timeout, then tried again
+	// once the other server fails.
+	servers[1].SetLatency(100 * time.Millisecond)
+	for len(pool.Clients()) == 2 {
+		_, err := pool.ServerBanner(context.Background())
+		require.NoError(t, err)
+	}
+	servers[1].SetLatency(0)
+	time.Sleep(100 * time.Millisecond)
+	servers[0].Close()
+
+	banner, err := pool.ServerBanner(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "b", banner)
+	assert.Equal(t, []*electrum.Client{clients[1]}, pool.Clients())
+}
+
+func TestPoolFailover(t *testing.T) {
+	t.Run("connection lost", func(t *testing.T) {
+		servers, clients := newPoolServers(t, 2)
+		pool := electrum.NewPool(&electrum.PoolConfig{}, clients[1], clients[0])
+		defer pool.Shutdown()
+
+		// The first request of a pool goes to its second client, the one of the first server.
+		servers[0].SetLatency(time.Second)
+		time.AfterFunc(50*time.Millisecond, servers[0].Disconnect)
+
+		balance, err := pool.GetBalance(context.Background(), testScripthash)
+		require.NoError(t, err)
+		assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1000}, balance)
+		assert.Equal(t, 1, servers[0].Calls("blockchain.scripthash.get_balance"))
+		assert.Equal(t, 1, servers[1].Calls("blockchain.scripthash.get_balance"))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		servers, clients := newPoolServers(t, 2, electrum.WithRequestTimeout(50*time.Millisecond))
+		pool := electrum.NewPool(&electrum.PoolConfig{}, clients[1], clients[0])
+		defer pool.Shutdown()
+
+		servers[0].SetLatency(time.Second)
+
+		balance, err := pool.GetBalance(context.Background(), testScripthash)
+		require.NoError(t, err)
+		assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1000}, balance)
+		assert.Equal(t, []*electrum.Client{clients[1]}, pool.Clients())
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		servers, clients := newPoolServers(t, 2)
+		pool := electrum.NewPool(&electrum.PoolConfig{}, clients[1], clients[0])
+		defer pool.Shutdown()
+
+		servers[0].SetLatency(time.Second)
+		time.AfterFunc(50*time.Millisecond, servers[0].Disconnect)
+
+		_, err := pool.BroadcastTransaction(context.Background(), "00")
+		assert.Error(t, err)
+		assert.Equal(t, 0, servers[1].Calls("blockchain.transaction.broadcast"))
+	})
+}
+
+func TestPoolLeavesClientErrors(t *testing.T) {
+	servers, clients := newPoolServers(t, 1)
+	pool := electrum.NewPool(&electrum.PoolConfig{}, clients...)
+	defer pool.Shutdown()
+
+	servers[0].Close()
+
+	// The connection loss ejects the client and still reaches its Error channel.
+	select {
+	case err := <-clients[0].Error:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("connection loss not reported")
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(pool.Clients()) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestPoolSubscribeNoHealthyServer(t *testing.T) {
+	pool := electrum.NewPool(&electrum.PoolConfig{})
+	defer pool.Shutdown()
+
+	sub, notifs, err := pool.SubscribeScripthash()
+	assert.ErrorIs(t, err, electrum.ErrNoHealthyServer)
+	assert.Nil(t, sub)
+	assert.Nil(t, notifs)
+
+	_, _, err = pool.SubscribeScripthashVerified(context.Background())
+	assert.ErrorIs(t, err, electrum.ErrNoHealthyServer)
+
+	_, _, err = pool.SubscribeScripthashEvents(context.Background())
+	assert.ErrorIs(t, err, electrum.ErrNoHealthyServer)
+}
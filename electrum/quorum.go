@@ -0,0 +1,224 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrQuorumNotReached throws an error if not enough servers agreed on a result.
+	ErrQuorumNotReached = errors.New("quorum not reached")
+
+	// ErrInvalidQuorum throws an error if the quorum parameters are inconsistent.
+	ErrInvalidQuorum = errors.New("quorum requires 0 < agree <= servers")
+)
+
+// QuorumGroup gathers the servers which returned the same normalized result.
+type QuorumGroup struct {
+	Result interface{}
+	Count  int
+}
+
+// QuorumError describes the disagreement between servers when a quorum has not been reached.
+type QuorumError struct {
+	Method   string
+	Required int
+	Queried  int
+	Groups   []QuorumGroup
+	Errors   []error
+}
+
+func (e *QuorumError) Error() string {
+	best := 0
+	if len(e.Groups) > 0 {
+		best = e.Groups[0].Count
+	}
+
+	return fmt.Sprintf("%s: %v, %d of %d servers required to agree, best agreement %d (%d distinct results, %d errors)",
+		e.Method, ErrQuorumNotReached, e.Required, e.Queried, best, len(e.Groups), len(e.Errors))
+}
+
+// Is allows matching a QuorumError with ErrQuorumNotReached.
+func (e *QuorumError) Is(target error) bool {
+	return target == ErrQuorumNotReached
+}
+
+// Quorum sends the same request to several servers of a pool and only returns a
+// result once enough of them agree, protecting callers from a single lying server.
+type Quorum struct {
+	pool    *Pool
+	servers int
+	agree   int
+}
+
+// Quorum returns a consensus view of the pool querying servers of its healthy clients
+// for every request and requiring agree of them to return the same result.
+func (p *Pool) Quorum(servers, agree int) (*Quorum, error) {
+	if agree <= 0 || agree > servers {
+		return nil, ErrInvalidQuorum
+	}
+
+	return &Quorum{
+		pool:    p,
+		servers: servers,
+		agree:   agree,
+	}, nil
+}
+
+type quorumAnswer struct {
+	result interface{}
+	err    error
+}
+
+// query runs fn on the quorum servers and returns the first result at least agree of
+// them returned. Results are compared by the JSON encoding of their normalized form,
+// given by normalize, but are returned as answered by the server.
+func (q *Quorum) query(ctx context.Context, method string, fn func(context.Context, *Client) (interface{}, error),
+	normalize func(interface{}) interface{}) (interface{}, error) {
+
+	candidates := q.pool.candidates()
+	if len(candidates) > q.servers {
+		candidates = candidates[:q.servers]
+	}
+	if len(candidates) < q.agree {
+		return nil, &QuorumError{
+			Method:   method,
+			Required: q.agree,
+			Queried:  len(candidates),
+			Errors:   []error{ErrNoHealthyServer},
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	answers := make(chan quorumAnswer, len(candidates))
+	for _, m := range candidates {
+		go func(c *Client) {
+			result, err := fn(ctx, c)
+			answers <- quorumAnswer{result: result, err: err}
+		}(m.client)
+	}
+
+	qErr := &QuorumError{
+		Method:   method,
+		Required: q.agree,
+		Queried:  len(candidates),
+	}
+	index := make(map[string]int)
+
+	for range candidates {
+		answer := <-answers
+		if answer.err != nil {
+			qErr.Errors = append(qErr.Errors, answer.err)
+			continue
+		}
+
+		normalized := answer.result
+		if normalize != nil {
+			normalized = normalize(answer.result)
+		}
+
+		key, err := json.Marshal(normalized)
+		if err != nil {
+			qErr.Errors = append(qErr.Errors, err)
+			continue
+		}
+
+		i, ok := index[string(key)]
+		if !ok {
+			i = len(qErr.Groups)
+			index[string(key)] = i
+			qErr.Groups = append(qErr.Groups, QuorumGroup{Result: answer.result})
+		}
+
+		qErr.Groups[i].Count++
+		if qErr.Groups[i].Count >= q.agree {
+			return answer.result, nil
+		}
+	}
+
+	sort.SliceStable(qErr.Groups, func(i, j int) bool {
+		return qErr.Groups[i].Count > qErr.Groups[j].Count
+	})
+
+	return nil, qErr
+}
+
+// GetBalance returns the balance for a scripthash once enough servers agree on it.
+func (q *Quorum) GetBalance(ctx context.Context, scripthash string) (GetBalanceResult, error) {
+	result, err := q.query(ctx, "blockchain.scripthash.get_balance", func(ctx context.Context, c *Client) (interface{}, error) {
+		return c.GetBalance(ctx, scripthash)
+	}, nil)
+	if err != nil {
+		return GetBalanceResult{}, err
+	}
+
+	return result.(GetBalanceResult), nil
+}
+
+// GetHistory returns the history for a scripthash once enough servers agree on it.
+// Histories are sorted by height and transaction hash before being compared, the history
+// returned keeping the order of the server, as hashed by its status.
+func (q *Quorum) GetHistory(ctx context.Context, scripthash string) ([]*GetMempoolResult, error) {
+	result, err := q.query(ctx, "blockchain.scripthash.get_history", func(ctx context.Context, c *Client) (interface{}, error) {
+		return c.GetHistory(ctx, scripthash)
+	}, normalizeHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*GetMempoolResult), nil
+}
+
+// GetMempool returns the unconfirmed transactions of a scripthash once enough servers agree on them.
+func (q *Quorum) GetMempool(ctx context.Context, scripthash string) ([]*GetMempoolResult, error) {
+	result, err := q.query(ctx, "blockchain.scripthash.get_mempool", func(ctx context.Context, c *Client) (interface{}, error) {
+		return c.GetMempool(ctx, scripthash)
+	}, normalizeHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*GetMempoolResult), nil
+}
+
+// ListUnspent returns the UTXOs of a scripthash once enough servers agree on them.
+// UTXOs are sorted by transaction hash and output position before being compared.
+func (q *Quorum) ListUnspent(ctx context.Context, scripthash string) ([]*ListUnspentResult, error) {
+	result, err := q.query(ctx, "blockchain.scripthash.listunspent", func(ctx context.Context, c *Client) (interface{}, error) {
+		return c.ListUnspent(ctx, scripthash)
+	}, normalizeUnspent)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*ListUnspentResult), nil
+}
+
+func normalizeHistory(result interface{}) interface{} {
+	normalized := append([]*GetMempoolResult{}, result.([]*GetMempoolResult)...)
+	sort.SliceStable(normalized, func(i, j int) bool {
+		if normalized[i].Height != normalized[j].Height {
+			return normalized[i].Height < normalized[j].Height
+		}
+		return normalized[i].Hash < normalized[j].Hash
+	})
+
+	return normalized
+}
+
+func normalizeUnspent(result interface{}) interface{} {
+	normalized := append([]*ListUnspentResult{}, result.([]*ListUnspentResult)...)
+	sort.SliceStable(normalized, func(i, j int) bool {
+		if normalized[i].Hash != normalized[j].Hash {
+			return normalized[i].Hash < normalized[j].Hash
+		}
+		return normalized[i].Position < normalized[j].Position
+	})
+
+	return normalized
+}
@@ -0,0 +1,90 @@
+package electrum_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newQuorumPool returns a pool over a server for each of balances, answering with it.
+func newQuorumPool(t *testing.T, balances ...int64) (*electrum.Pool, []*electrumtest.Server) {
+	servers := make([]*electrumtest.Server, len(balances))
+	clients := make([]*electrum.Client, len(balances))
+	for i, balance := range balances {
+		servers[i] = electrumtest.NewServer()
+		servers[i].SetResult("blockchain.scripthash.get_balance", map[string]int64{"confirmed": balance})
+		t.Cleanup(servers[i].Close)
+
+		client, err := servers[i].NewClient(context.Background())
+		require.NoError(t, err)
+		clients[i] = client
+	}
+
+	pool := electrum.NewPool(&electrum.PoolConfig{}, clients...)
+	t.Cleanup(pool.Shutdown)
+
+	return pool, servers
+}
+
+func TestQuorumAgree(t *testing.T) {
+	pool, _ := newQuorumPool(t, 1000, 1000, 5)
+
+	quorum, err := pool.Quorum(3, 2)
+	require.NoError(t, err)
+
+	balance, err := quorum.GetBalance(context.Background(), testScripthash)
+	require.NoError(t, err)
+	assert.Equal(t, electrum.GetBalanceResult{Confirmed: 1000}, balance)
+}
+
+func TestQuorumDisagree(t *testing.T) {
+	pool, _ := newQuorumPool(t, 1000, 1000, 5)
+
+	quorum, err := pool.Quorum(3, 3)
+	require.NoError(t, err)
+
+	_, err = quorum.GetBalance(context.Background(), testScripthash)
+	assert.ErrorIs(t, err, electrum.ErrQuorumNotReached)
+
+	var qErr *electrum.QuorumError
+	require.ErrorAs(t, err, &qErr)
+	assert.Equal(t, 3, qErr.Queried)
+	require.Len(t, qErr.Groups, 2)
+	assert.Equal(t, electrum.QuorumGroup{Result: electrum.GetBalanceResult{Confirmed: 1000}, Count: 2}, qErr.Groups[0])
+	assert.Equal(t, 1, qErr.Groups[1].Count)
+}
+
+func TestQuorumTooFewServers(t *testing.T) {
+	pool, _ := newQuorumPool(t, 1000, 1000)
+
+	quorum, err := pool.Quorum(3, 3)
+	require.NoError(t, err)
+
+	_, err = quorum.GetBalance(context.Background(), testScripthash)
+	assert.ErrorIs(t, err, electrum.ErrQuorumNotReached)
+
+	var qErr *electrum.QuorumError
+	require.ErrorAs(t, err, &qErr)
+	assert.Equal(t, 2, qErr.Queried)
+	assert.Equal(t, 3, qErr.Required)
+}
+
+func TestQuorumHistoryOrder(t *testing.T) {
+	pool, servers := newQuorumPool(t, 0, 0)
+
+	// The transactions of block 10 are in block order, which is not their hash order.
+	history := []*electrum.GetMempoolResult{{Hash: "bb", Height: 10}, {Hash: "aa", Height: 10}, {Hash: "cc", Height: 0}}
+	servers[0].SetResult("blockchain.scripthash.get_history", history)
+	servers[1].SetResult("blockchain.scripthash.get_history", history)
+
+	quorum, err := pool.Quorum(2, 2)
+	require.NoError(t, err)
+
+	result, err := quorum.GetHistory(context.Background(), testScripthash)
+	require.NoError(t, err)
+	assert.Equal(t, history, result)
+}
@@ -0,0 +1,280 @@
+package electrum
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+var (
+	// ErrReconnectFailed throws an error if the client gave up reconnecting to the remote server.
+	ErrReconnectFailed = errors.New("reconnect attempts exhausted")
+)
+
+// TransportDialer opens a new transport to the remote server. It is used by the client
+// to establish the initial connection and every following reconnection.
+type TransportDialer func(ctx context.Context) (Transport, error)
+
+// TCPDialer returns a TransportDialer opening TCP connections to addr, through dialer when given.
+func TCPDialer(addr string, dialer ...Dialer) TransportDialer {
+	return func(ctx context.Context) (Transport, error) {
+		return NewTCPTransport(ctx, addr, dialer...)
+	}
+}
+
+// SSLDialer returns a TransportDialer opening SSL connections to addr, through dialer when given.
+func SSLDialer(addr string, config *tls.Config, dialer ...Dialer) TransportDialer {
+	return func(ctx context.Context) (Transport, error) {
+		return NewSSLTransport(ctx, addr, config, dialer...)
+	}
+}
+
+// ReconnectConfig controls how a client redials the remote server after the
+// connection has been lost.
+type ReconnectConfig struct {
+	// InitialBackoff is the delay before the first reconnection attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two reconnection attempts.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after every failed attempt, at least 1.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction, between 0 and 1.
+	Jitter float64
+	// MaxAttempts is the number of attempts before giving up, 0 means retry forever.
+	MaxAttempts int
+	// DialTimeout bounds a single dial and the following handshake.
+	DialTimeout time.Duration
+}
+
+// DefaultReconnectConfig returns the reconnection settings used when none are provided.
+func DefaultReconnectConfig() *ReconnectConfig {
+	return &ReconnectConfig{
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     time.Minute,
+		Multiplier:     2,
+		Jitter:         0.2,
+		DialTimeout:    30 * time.Second,
+	}
+}
+
+// backoff returns the delay before the reconnection attempt following attempt failed ones.
+// A Multiplier below 1 is treated as 1, so that delays never shrink, and every delay,
+// including the first one, is capped by MaxBackoff.
+func (r *ReconnectConfig) backoff(attempt int) time.Duration {
+	multiplier := r.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(r.InitialBackoff)
+	for i := 0; i < attempt && (r.MaxBackoff <= 0 || delay < float64(r.MaxBackoff)); i++ {
+		delay *= multiplier
+	}
+	if r.MaxBackoff > 0 && delay > float64(r.MaxBackoff) {
+		delay = float64(r.MaxBackoff)
+	}
+
+	if r.Jitter > 0 {
+		delay += delay * r.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// NewReconnectingClient initialize a new client for remote server using dialer, and
+// transparently reconnects with exponential backoff whenever the connection is lost.
+// After every reconnection the protocol version is negotiated again and the headers,
+// scripthash and masternode subscriptions are replayed on the new connection, so the
+// notification channels returned earlier keep delivering.
+func NewReconnectingClient(ctx context.Context, dialer TransportDialer, config *ReconnectConfig) (*Client, error) {
+	transport, err := dialer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		config = DefaultReconnectConfig()
+	}
+
+	c := newClient(transport, &clientOptions{})
+	c.dialer = dialer
+	c.reconnect = config
+	err = c.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// redial replaces the failed transport with a new one. It returns false if the
+// client has been shut down or all attempts have been exhausted.
+func (s *Client) redial(failed Transport, cause error) bool {
+	s.log(LogInfo, "connection lost", LogField{"error", cause})
+	_ = failed.Close()
+	s.failPending(cause)
+	s.notifyError(cause)
+
+	for attempt := 0; s.reconnect.MaxAttempts == 0 || attempt < s.reconnect.MaxAttempts; attempt++ {
+		select {
+		case <-s.quit:
+			return false
+		case <-time.After(s.reconnect.backoff(attempt)):
+		}
+
+		ctx, cancel := s.dialContext()
+		transport, err := s.dialer(ctx)
+		cancel()
+		if err != nil {
+			s.log(LogInfo, "reconnect failed", LogField{"attempt", attempt + 1}, LogField{"error", err})
+			s.notifyError(err)
+			continue
+		}
+
+		if s.IsShutdown() {
+			_ = transport.Close()
+			return false
+		}
+
+		s.setVersion("", "")
+		s.setTransport(transport)
+		s.log(LogInfo, "reconnected", LogField{"attempt", attempt + 1})
+		go s.restore()
+
+		return true
+	}
+
+	s.log(LogError, "reconnect attempts exhausted", LogField{"attempts", s.reconnect.MaxAttempts})
+	s.notifyError(ErrReconnectFailed)
+
+	return false
+}
+
+func (s *Client) dialContext() (context.Context, context.CancelFunc) {
+	if s.reconnect.DialTimeout > 0 {
+		return context.WithTimeout(context.Background(), s.reconnect.DialTimeout)
+	}
+
+	return context.WithCancel(context.Background())
+}
+
+// restore negotiates the protocol version and replays every subscription on a
+// freshly dialed connection. It must run outside of the listen loop.
+func (s *Client) restore() {
+	ctx, cancel := s.dialContext()
+	defer cancel()
+
+	if _, _, err := s.negotiate(ctx); err != nil {
+		s.notifyError(err)
+		return
+	}
+
+	s.resubscribersLock.Lock()
+	resubscribers := append([]resubscriber(nil), s.resubscribers...)
+	s.resubscribersLock.Unlock()
+
+	for _, r := range resubscribers {
+		if err := r.resubscribe(ctx); err != nil {
+			s.notifyError(err)
+		}
+	}
+}
+
+// resubscriber replays a subscription after a reconnection.
+type resubscriber struct {
+	id          uint64
+	resubscribe func(context.Context) error
+}
+
+// onReconnect registers a function replaying a subscription after a reconnection, and
+// returns its id for removeResubscriber(), 0 if the client does not reconnect.
+func (s *Client) onReconnect(resubscribe func(context.Context) error) uint64 {
+	if s.reconnect == nil {
+		return 0
+	}
+
+	s.resubscribersLock.Lock()
+	defer s.resubscribersLock.Unlock()
+
+	s.nextResubscriber++
+	s.resubscribers = append(s.resubscribers, resubscriber{id: s.nextResubscriber, resubscribe: resubscribe})
+
+	return s.nextResubscriber
+}
+
+// removeResubscriber stops replaying the subscription registered with id by onReconnect().
+func (s *Client) removeResubscriber(id uint64) {
+	if id == 0 {
+		return
+	}
+
+	s.resubscribersLock.Lock()
+	defer s.resubscribersLock.Unlock()
+
+	for i, r := range s.resubscribers {
+		if r.id == id {
+			s.resubscribers = append(s.resubscribers[:i], s.resubscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// failPending releases every request waiting on the lost connection.
+func (s *Client) failPending(err error) {
+	s.handlersLock.RLock()
+	defer s.handlersLock.RUnlock()
+
+	for _, c := range s.handlers {
+		select {
+		case c <- &container{err: err}:
+		default:
+		}
+	}
+}
+
+// notifyError reports err on the Error channel without blocking the client when nobody
+// is listening, replacing the unread error so a late reader gets the latest one.
+func (s *Client) notifyError(err error) {
+	s.watchError(err)
+
+	for {
+		select {
+		case s.Error <- err:
+			return
+		default:
+		}
+
+		select {
+		case <-s.Error:
+		default:
+		}
+	}
+}
+
+// watchErrors returns a channel receiving the errors reported by the client, so internal
+// consumers such as Pool can follow them while leaving the Error channel to the user.
+func (s *Client) watchErrors() <-chan error {
+	c := make(chan error, 1)
+
+	s.errorWatchersLock.Lock()
+	s.errorWatchers = append(s.errorWatchers, c)
+	s.errorWatchersLock.Unlock()
+
+	return c
+}
+
+// watchError hands err to the channels returned by watchErrors(), without blocking when
+// the previous error is still pending.
+func (s *Client) watchError(err error) {
+	s.errorWatchersLock.Lock()
+	defer s.errorWatchersLock.Unlock()
+
+	for _, c := range s.errorWatchers {
+		select {
+		case c <- err:
+		default:
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package electrum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	r := &ReconnectConfig{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}
+	assert.Equal(t, time.Second, r.backoff(0))
+	assert.Equal(t, 4*time.Second, r.backoff(2))
+	assert.Equal(t, 10*time.Second, r.backoff(10))
+
+	// A multiplier below 1 never shrinks the delay.
+	for _, multiplier := range []float64{0, 0.5} {
+		r.Multiplier = multiplier
+		assert.Equal(t, time.Second, r.backoff(5))
+	}
+
+	// The first delay is capped too.
+	r = &ReconnectConfig{InitialBackoff: time.Minute, MaxBackoff: 10 * time.Second, Multiplier: 2}
+	assert.Equal(t, 10*time.Second, r.backoff(0))
+}
@@ -0,0 +1,172 @@
+package electrum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JSON-RPC error codes returned by ElectrumX and compatible servers.
+const (
+	// CodeBadRequest is returned for invalid params, including rejected transactions.
+	CodeBadRequest = 1
+
+	// CodeDaemonError is returned when the node behind the remote server failed a call.
+	CodeDaemonError = 2
+
+	// CodeInvalidRequest is the JSON-RPC code of malformed requests.
+	CodeInvalidRequest = -32600
+
+	// CodeMethodNotFound is the JSON-RPC code of unknown methods.
+	CodeMethodNotFound = -32601
+
+	// CodeInvalidParams is the JSON-RPC code of invalid params.
+	CodeInvalidParams = -32602
+)
+
+var (
+	// ErrBadRequest throws an error if the remote server rejected the params of a call.
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrDaemonError throws an error if the node behind the remote server failed a call.
+	ErrDaemonError = errors.New("daemon error")
+
+	// ErrTxRejected throws an error if a broadcast transaction has been rejected by the node.
+	ErrTxRejected = errors.New("transaction rejected")
+
+	// ErrNotFound throws an error if the requested transaction or block is unknown to the node.
+	ErrNotFound = errors.New("not found")
+
+	// ErrMethodNotFound throws an error if the remote server does not know the method of a call.
+	ErrMethodNotFound = errors.New("method not found")
+)
+
+// lookupMethods are the calls looking up a transaction or a block header, whose errors may
+// match ErrNotFound.
+var lookupMethods = map[string]bool{
+	"blockchain.block.header":            true,
+	"blockchain.block.headers":           true,
+	"blockchain.transaction.get":         true,
+	"blockchain.transaction.get_merkle":  true,
+	"blockchain.transaction.id_from_pos": true,
+}
+
+// RPCError is an error returned by the remote server in answer to a call. It matches
+// ErrBadRequest, ErrDaemonError, ErrTxRejected, ErrNotFound and ErrMethodNotFound with
+// errors.Is() depending on its code, the method of the call and its message.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+
+	// Method is the method of the call the error answers, set by the client. When it is
+	// empty, ErrTxRejected and ErrNotFound are matched from the message alone.
+	Method string `json:"-"`
+
+	// text is set for the errors sent as a plain string rather than an object.
+	text bool
+}
+
+func (e *RPCError) Error() string {
+	if e.text {
+		return e.Message
+	}
+
+	return fmt.Sprintf("errNo: %d, errMsg: %s", e.Code, e.Message)
+}
+
+// Is reports whether the error matches one of the sentinel errors.
+func (e *RPCError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.Code == CodeBadRequest || e.Code == CodeInvalidRequest || e.Code == CodeInvalidParams
+	case ErrDaemonError:
+		return e.Code == CodeDaemonError
+	case ErrMethodNotFound:
+		return e.Code == CodeMethodNotFound
+	case ErrTxRejected:
+		if e.Method != "" {
+			return e.Method == "blockchain.transaction.broadcast" && e.Code == CodeBadRequest
+		}
+		return strings.Contains(e.Message, "rejected by network rules")
+	case ErrNotFound:
+		if e.Method != "" && !lookupMethods[e.Method] {
+			return false
+		}
+		msg := strings.ToLower(e.Message)
+		return strings.Contains(msg, "no such mempool or blockchain transaction") ||
+			strings.Contains(msg, "not found") || strings.Contains(msg, "unknown transaction")
+	}
+
+	return false
+}
+
+// withMethod records the method of the call err answers, when it has been returned by the
+// remote server.
+func withMethod(err error, method string) error {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Method == "" {
+		rpcErr.Method = method
+	}
+
+	return err
+}
+
+// decodeRPCError decodes the error field of a response, which servers send either as a
+// {"code", "message", "data"} object or as a plain string. It returns nil if there is no error.
+func decodeRPCError(raw json.RawMessage) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	rpcErr := &RPCError{}
+	if raw[0] == '"' {
+		rpcErr.text = true
+		if err := json.Unmarshal(raw, &rpcErr.Message); err != nil {
+			return err
+		}
+		return rpcErr
+	}
+
+	if err := json.Unmarshal(raw, rpcErr); err != nil {
+		return err
+	}
+
+	return rpcErr
+}
+
+// TxRejectedError is returned by BroadcastTransaction() when the node rejected the
+// transaction. It matches ErrTxRejected, and unwraps to the RPCError returned by the server.
+type TxRejectedError struct {
+	// Reason is the reject reason given by the node, such as "min relay fee not met".
+	Reason string
+	Err    *RPCError
+}
+
+func (e *TxRejectedError) Error() string {
+	return "transaction rejected: " + e.Reason
+}
+
+// Is reports whether target is ErrTxRejected.
+func (e *TxRejectedError) Is(target error) bool {
+	return target == ErrTxRejected
+}
+
+func (e *TxRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// rejectReason extracts the reject reason of the node from the error returned for a broadcast,
+// ElectrumX wrapping it between a generic sentence and the raw transaction.
+func rejectReason(message string) string {
+	reason := message
+	if i := strings.Index(reason, "\n\n"); i >= 0 {
+		reason = reason[i+2:]
+	}
+	if i := strings.LastIndex(reason, "\n["); i >= 0 {
+		reason = reason[:i]
+	}
+
+	return strings.TrimSpace(reason)
+}
@@ -0,0 +1,74 @@
+package electrum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRPCError(t *testing.T) {
+	assert.NoError(t, decodeRPCError(nil))
+	assert.NoError(t, decodeRPCError(json.RawMessage("null")))
+
+	err := decodeRPCError(json.RawMessage(`{"code":2,"message":"daemon error: DaemonError({'code': -5, 'message': 'No such mempool or blockchain transaction. Use gettransaction for wallet transactions.'})"}`))
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, CodeDaemonError, rpcErr.Code)
+	assert.ErrorIs(t, err, ErrDaemonError)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrBadRequest)
+
+	err = decodeRPCError(json.RawMessage(`{"code":-32601,"message":"unknown method \"blockchain.foo\""}`))
+	assert.ErrorIs(t, err, ErrMethodNotFound)
+	assert.NotErrorIs(t, err, ErrNotImplemented)
+	assert.Equal(t, `errNo: -32601, errMsg: unknown method "blockchain.foo"`, err.Error())
+
+	// Errors sent as a plain string keep their text.
+	err = decodeRPCError(json.RawMessage(`"server busy"`))
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "server busy", rpcErr.Message)
+	assert.Equal(t, "server busy", err.Error())
+}
+
+func TestBroadcastTransactionRejected(t *testing.T) {
+	c := &Client{
+		interceptor: func(ctx context.Context, method string, params []interface{}, v interface{}, invoker Invoker) error {
+			return decodeRPCError(json.RawMessage(`{"code":1,"message":"the transaction was rejected by network rules.\n\nmin relay fee not met, 100 < 141\n[0100000001]"}`))
+		},
+	}
+
+	_, err := c.BroadcastTransaction(context.Background(), "0100000001")
+	assert.ErrorIs(t, err, ErrTxRejected)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	var rejected *TxRejectedError
+	require.True(t, errors.As(err, &rejected))
+	assert.Equal(t, "min relay fee not met, 100 < 141", rejected.Reason)
+}
+
+func TestRPCErrorMethod(t *testing.T) {
+	rejected := &RPCError{Code: CodeBadRequest, Message: "bad-txns-inputs-missingorspent", Method: "blockchain.transaction.broadcast"}
+	assert.ErrorIs(t, rejected, ErrTxRejected)
+	assert.Equal(t, "bad-txns-inputs-missingorspent", rejectReason(rejected.Message))
+
+	// Only bad requests to broadcast are rejected transactions.
+	daemon := &RPCError{Code: CodeDaemonError, Message: "the transaction was rejected by network rules.", Method: "blockchain.transaction.broadcast"}
+	assert.NotErrorIs(t, daemon, ErrTxRejected)
+	other := &RPCError{Code: CodeBadRequest, Message: "the transaction was rejected by network rules.", Method: "blockchain.scripthash.get_balance"}
+	assert.NotErrorIs(t, other, ErrTxRejected)
+
+	// Only lookups of transactions and headers are not found.
+	notFound := &RPCError{Code: CodeDaemonError, Message: "transaction not found", Method: "blockchain.transaction.get"}
+	assert.ErrorIs(t, notFound, ErrNotFound)
+	notFound = &RPCError{Code: CodeBadRequest, Message: "height 1000 not found", Method: "blockchain.block.header"}
+	assert.ErrorIs(t, notFound, ErrNotFound)
+	other = &RPCError{Code: CodeBadRequest, Message: "peer not found", Method: "server.add_peer"}
+	assert.NotErrorIs(t, other, ErrNotFound)
+
+	assert.ErrorIs(t, withMethod(&RPCError{Code: CodeBadRequest}, "blockchain.transaction.broadcast"), ErrTxRejected)
+	assert.Equal(t, ErrTimeout, withMethod(ErrTimeout, "blockchain.transaction.broadcast"))
+}
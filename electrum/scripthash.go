@@ -1,6 +1,10 @@
 package electrum
 
-import "context"
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/btcutil"
+)
 
 // GetBalanceResp represents the response to GetBalance().
 type GetBalanceResp struct {
@@ -9,8 +13,27 @@ type GetBalanceResp struct {
 
 // GetBalanceResult represents the content of the result field in the response to GetBalance().
 type GetBalanceResult struct {
-	Confirmed   float64 `json:"confirmed"`
-	Unconfirmed float64 `json:"unconfirmed"`
+	Confirmed   btcutil.Amount `json:"confirmed"`
+	Unconfirmed btcutil.Amount `json:"unconfirmed"`
+}
+
+// Total returns the sum of the confirmed and unconfirmed balances.
+func (r GetBalanceResult) Total() btcutil.Amount {
+	return r.Confirmed + r.Unconfirmed
+}
+
+// ConfirmedFloat returns the confirmed balance in satoshis as a float64.
+//
+// Deprecated: use Confirmed, which holds the exact amount.
+func (r GetBalanceResult) ConfirmedFloat() float64 {
+	return float64(r.Confirmed)
+}
+
+// UnconfirmedFloat returns the unconfirmed balance in satoshis as a float64.
+//
+// Deprecated: use Unconfirmed, which holds the exact amount.
+func (r GetBalanceResult) UnconfirmedFloat() float64 {
+	return float64(r.Unconfirmed)
 }
 
 // GetBalance returns the confirmed and unconfirmed balance for a scripthash.
@@ -91,19 +91,19 @@ type ServerVersionResp struct {
 }
 
 // ServerVersion identify the client to the server, and negotiate the protocol version.
-// This call must be sent first, or the server will default to an older protocol version.
+// The version is negotiated when the client connects, within the range set by
+// WithProtocolVersions(), and the agreed versions are returned without sending the call again.
+// This also applies to the clients of NewClientTCP(), NewClientSSL() and NewClientWS(), which
+// negotiate from ProtocolVersionMin to ProtocolVersion.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#server-version
 func (s *Client) ServerVersion(ctx context.Context) (serverVer, protocolVer string, err error) {
-	var resp ServerVersionResp
-
-	err = s.request(ctx, "server.version", []interface{}{ClientVersion, ProtocolVersion}, &resp)
-	if err != nil {
-		serverVer = ""
-		protocolVer = ""
-	} else {
-		serverVer = resp.Result[0]
-		protocolVer = resp.Result[1]
+	s.versionLock.RLock()
+	serverVer, protocolVer = s.serverVersion, s.negotiatedVersion
+	s.versionLock.RUnlock()
+
+	if protocolVer != "" {
+		return serverVer, protocolVer, nil
 	}
 
-	return
+	return s.negotiate(ctx)
 }
@@ -0,0 +1,393 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+var (
+	// ErrStatusMismatch throws an error if the status notified by the remote server does not
+	// match the status of the history it returns.
+	ErrStatusMismatch = errors.New("scripthash status does not match history")
+)
+
+// StatusMismatchError describes a notified status differing from the status computed from
+// the history of the scripthash.
+type StatusMismatchError struct {
+	Scripthash string
+	Notified   string
+	Computed   string
+}
+
+func (e *StatusMismatchError) Error() string {
+	return fmt.Sprintf("%v: scripthash %s, notified %s, computed %s",
+		ErrStatusMismatch, e.Scripthash, e.Notified, e.Computed)
+}
+
+// Is allows matching a StatusMismatchError with ErrStatusMismatch.
+func (e *StatusMismatchError) Is(target error) bool {
+	return target == ErrStatusMismatch
+}
+
+// SortHistory orders a history the way the remote server hashes it: the confirmed
+// transactions in blockchain order, followed by the mempool transactions, the ones with
+// confirmed inputs (height 0) before the ones with unconfirmed inputs (height -1), then
+// by transaction hash in internal byte order.
+func SortHistory(history []*GetMempoolResult) {
+	sort.SliceStable(history, func(i, j int) bool {
+		a, b := history[i], history[j]
+		if a.Height > 0 || b.Height > 0 {
+			return a.Height > 0 && (b.Height <= 0 || a.Height < b.Height)
+		}
+		if a.Height != b.Height {
+			return a.Height > b.Height
+		}
+
+		return compareTxHashes(a.Hash, b.Hash) < 0
+	})
+}
+
+// compareTxHashes compares two hex encoded transaction hashes in internal byte order.
+func compareTxHashes(a, b string) int {
+	x, errX := chainhash.NewHashFromStr(a)
+	y, errY := chainhash.NewHashFromStr(b)
+	if errX != nil || errY != nil {
+		return strings.Compare(a, b)
+	}
+
+	return bytes.Compare(x[:], y[:])
+}
+
+// ScripthashStatus computes the status of a scripthash from its history as returned by
+// GetHistory(), the hex encoded sha256 of the "tx_hash:height:" concatenation of its
+// transactions ordered by SortHistory(). An empty history has an empty status, sent as null
+// by the remote server.
+// https://electrumx.readthedocs.io/en/latest/protocol-basics.html#status
+func ScripthashStatus(history []*GetMempoolResult) string {
+	sorted := append([]*GetMempoolResult(nil), history...)
+	SortHistory(sorted)
+
+	return historyStatus(sorted)
+}
+
+// historyStatus computes the status of history, keeping the order of its transactions.
+func historyStatus(history []*GetMempoolResult) string {
+	if len(history) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	for _, h := range history {
+		fmt.Fprintf(&buf, "%s:%d:", h.Hash, h.Height)
+	}
+	sum := sha256.Sum256([]byte(buf.String()))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// matchStatus reports whether status is the status of history hashed in the order the
+// remote server returned it, which is the order the server hashes, or else in the
+// SortHistory() order, for the servers not returning their history in that order.
+func matchStatus(history []*GetMempoolResult, status string) bool {
+	return historyStatus(history) == status || ScripthashStatus(history) == status
+}
+
+// HistoryDiff describes how the history of a scripthash changed between two notifications.
+type HistoryDiff struct {
+	Scripthash string
+	Status     string
+	// History is the whole new history.
+	History []*GetMempoolResult
+
+	// Added lists the transactions new to the history.
+	Added []*GetMempoolResult
+	// Removed lists the transactions no longer in the history, with their previous height.
+	Removed []*GetMempoolResult
+	// Confirmed lists the transactions confirmed since the previous history, or confirmed
+	// again at another height after a reorganization.
+	Confirmed []*GetMempoolResult
+	// Unconfirmed lists the confirmed transactions moved back to the mempool by a reorganization.
+	Unconfirmed []*GetMempoolResult
+}
+
+// Empty reports whether no transaction was added, removed, confirmed or unconfirmed.
+func (d *HistoryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Confirmed) == 0 && len(d.Unconfirmed) == 0
+}
+
+// DiffHistory compares two histories of scripthash, previous being nil for the first one.
+func DiffHistory(scripthash string, previous, history []*GetMempoolResult) *HistoryDiff {
+	diff := &HistoryDiff{
+		Scripthash: scripthash,
+		Status:     ScripthashStatus(history),
+		History:    history,
+	}
+
+	heights := make(map[string]int32, len(previous))
+	for _, h := range previous {
+		heights[h.Hash] = h.Height
+	}
+
+	for _, h := range history {
+		height, ok := heights[h.Hash]
+		delete(heights, h.Hash)
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, h)
+		case h.Height > 0 && h.Height != height:
+			diff.Confirmed = append(diff.Confirmed, h)
+		case h.Height <= 0 && height > 0:
+			diff.Unconfirmed = append(diff.Unconfirmed, h)
+		}
+	}
+
+	for _, h := range previous {
+		if _, ok := heights[h.Hash]; ok {
+			diff.Removed = append(diff.Removed, h)
+		}
+	}
+
+	return diff
+}
+
+// VerifiedSubscription follows scripthashes like ScripthashSubscription, but fetches the
+// history of a scripthash on each of its notifications, checks the notified status matches
+// it, and delivers the changes since the previous history.
+type VerifiedSubscription struct {
+	sub     *ScripthashSubscription
+	client  *Client
+	diffs   chan *HistoryDiff
+	errors  chan error
+	history map[string][]*GetMempoolResult
+	status  map[string]string
+	lock    sync.Mutex
+
+	// pending holds the latest notified status of each scripthash not verified yet,
+	// verified in the order of pendingOrder by run().
+	pending      map[string]string
+	pendingOrder []string
+	pendingLock  sync.Mutex
+	wake         chan struct{}
+}
+
+// SubscribeScripthashVerified returns a subscription delivering a HistoryDiff each time the
+// history of one of its scripthashes changes, the first one listing the whole history as
+// added. Notifications are followed until ctx is done. A scripthash notified again while
+// it is being verified is verified once more with its latest status only. A notified status
+// not matching the history is reported on Errors() as a *StatusMismatchError, and its diff
+// is not delivered.
+func (s *Client) SubscribeScripthashVerified(ctx context.Context) (*VerifiedSubscription, <-chan *HistoryDiff) {
+	sub, notifs := s.SubscribeScripthash()
+
+	v := &VerifiedSubscription{
+		sub:     sub,
+		client:  s,
+		diffs:   make(chan *HistoryDiff, s.notifBuffer()),
+		errors:  make(chan error, 1),
+		history: make(map[string][]*GetMempoolResult),
+		status:  make(map[string]string),
+		pending: make(map[string]string),
+		wake:    make(chan struct{}, 1),
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.quit:
+				return
+			case notif := <-notifs:
+				v.queue(notif.Params[0], notif.Params[1])
+			}
+		}
+	}()
+	go v.run(ctx)
+
+	return v, v.diffs
+}
+
+// queue records status as the latest notified status of scripthash, to be verified by run().
+func (v *VerifiedSubscription) queue(scripthash, status string) {
+	v.pendingLock.Lock()
+	if _, ok := v.pending[scripthash]; !ok {
+		v.pendingOrder = append(v.pendingOrder, scripthash)
+	}
+	v.pending[scripthash] = status
+	v.pendingLock.Unlock()
+
+	select {
+	case v.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next pops the oldest scripthash waiting to be verified along with its latest status.
+func (v *VerifiedSubscription) next() (string, string, bool) {
+	v.pendingLock.Lock()
+	defer v.pendingLock.Unlock()
+
+	if len(v.pendingOrder) == 0 {
+		return "", "", false
+	}
+	scripthash := v.pendingOrder[0]
+	v.pendingOrder = v.pendingOrder[1:]
+	status := v.pending[scripthash]
+	delete(v.pending, scripthash)
+
+	return scripthash, status, true
+}
+
+// queued reports whether a newer status of scripthash is waiting to be verified.
+func (v *VerifiedSubscription) queued(scripthash string) bool {
+	v.pendingLock.Lock()
+	defer v.pendingLock.Unlock()
+
+	_, ok := v.pending[scripthash]
+
+	return ok
+}
+
+// run verifies the pending statuses and delivers their diffs until ctx is done or the
+// client shuts down.
+func (v *VerifiedSubscription) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-v.client.quit:
+			return
+		case <-v.wake:
+		}
+
+		for {
+			scripthash, status, ok := v.next()
+			if !ok {
+				break
+			}
+
+			diff, err := v.verify(ctx, scripthash, status)
+			if err != nil {
+				// A status outdated by a newer one may not match the history anymore.
+				if !errors.Is(err, ErrStatusMismatch) || !v.queued(scripthash) {
+					v.notifyError(err)
+				}
+				continue
+			}
+			if diff == nil {
+				continue
+			}
+
+			select {
+			case v.diffs <- diff:
+			case <-ctx.Done():
+				return
+			case <-v.client.quit:
+				return
+			}
+		}
+	}
+}
+
+// verify fetches the history of scripthash and returns its changes, or nil if status is
+// already the known one.
+func (v *VerifiedSubscription) verify(ctx context.Context, scripthash, status string) (*HistoryDiff, error) {
+	v.lock.Lock()
+	known, ok := v.status[scripthash]
+	previous := v.history[scripthash]
+	v.lock.Unlock()
+
+	if ok && known == status {
+		return nil, nil
+	}
+
+	history, err := v.client.GetHistory(ctx, scripthash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !matchStatus(history, status) {
+		return nil, &StatusMismatchError{Scripthash: scripthash, Notified: status, Computed: historyStatus(history)}
+	}
+	diff := DiffHistory(scripthash, previous, history)
+	diff.Status = status
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if _, ok := v.status[scripthash]; !ok {
+		// The scripthash has been removed while its history was fetched.
+		return nil, nil
+	}
+	v.status[scripthash] = status
+	v.history[scripthash] = history
+
+	if diff.Empty() {
+		return nil, nil
+	}
+
+	return diff, nil
+}
+
+func (v *VerifiedSubscription) notifyError(err error) {
+	select {
+	case v.errors <- err:
+	default:
+	}
+}
+
+// Add subscribes to scripthash, optionally recording the address it was derived from.
+func (v *VerifiedSubscription) Add(ctx context.Context, scripthash string, address ...string) error {
+	v.lock.Lock()
+	if _, ok := v.status[scripthash]; !ok {
+		v.status[scripthash] = ""
+	}
+	v.lock.Unlock()
+
+	err := v.sub.Add(ctx, scripthash, address...)
+	if err != nil {
+		v.lock.Lock()
+		delete(v.status, scripthash)
+		v.lock.Unlock()
+	}
+
+	return err
+}
+
+// Remove stops delivering the changes of scripthash.
+func (v *VerifiedSubscription) Remove(scripthash string) error {
+	v.lock.Lock()
+	delete(v.status, scripthash)
+	delete(v.history, scripthash)
+	v.lock.Unlock()
+
+	return v.sub.Remove(scripthash)
+}
+
+// GetAddress returns the address recorded for scripthash by Add().
+func (v *VerifiedSubscription) GetAddress(scripthash string) (string, error) {
+	return v.sub.GetAddress(scripthash)
+}
+
+// History returns the last verified history of scripthash.
+func (v *VerifiedSubscription) History(scripthash string) []*GetMempoolResult {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	return append([]*GetMempoolResult(nil), v.history[scripthash]...)
+}
+
+// Errors returns chan to errors encountered while verifying the notifications.
+func (v *VerifiedSubscription) Errors() <-chan error {
+	return v.errors
+}
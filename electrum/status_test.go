@@ -0,0 +1,68 @@
+package electrum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScripthashStatus(t *testing.T) {
+	confirmed := strings.Repeat("ff", 32)
+	// Ordered differently as hex strings and in internal byte order.
+	first := "01" + strings.Repeat("00", 31)
+	second := strings.Repeat("00", 31) + "01"
+	unconfirmed := strings.Repeat("00", 32)
+
+	history := []*GetMempoolResult{
+		{Hash: unconfirmed, Height: -1},
+		{Hash: second, Height: 0},
+		{Hash: first, Height: 0},
+		{Hash: confirmed, Height: 100},
+	}
+
+	sum := sha256.Sum256([]byte(confirmed + ":100:" + first + ":0:" + second + ":0:" + unconfirmed + ":-1:"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), ScripthashStatus(history))
+	assert.Equal(t, unconfirmed, history[0].Hash, "history is not reordered")
+	assert.Equal(t, "", ScripthashStatus(nil))
+}
+
+func TestMatchStatus(t *testing.T) {
+	history := []*GetMempoolResult{
+		{Hash: strings.Repeat("02", 32), Height: 0},
+		{Hash: strings.Repeat("01", 32), Height: 0},
+	}
+	sorted := []*GetMempoolResult{history[1], history[0]}
+
+	// The history is hashed in the order of the remote server, then in the sorted order.
+	assert.True(t, matchStatus(history, historyStatus(history)))
+	assert.True(t, matchStatus(history, historyStatus(sorted)))
+	assert.NotEqual(t, historyStatus(history), historyStatus(sorted))
+	assert.False(t, matchStatus(history, historyStatus(history[:1])))
+}
+
+func TestDiffHistory(t *testing.T) {
+	previous := []*GetMempoolResult{
+		{Hash: "a", Height: 10},
+		{Hash: "b", Height: 11},
+		{Hash: "c", Height: 0},
+		{Hash: "d", Height: 0},
+	}
+	history := []*GetMempoolResult{
+		{Hash: "a", Height: 10},
+		{Hash: "c", Height: 12},
+		{Hash: "b", Height: 0},
+		{Hash: "e", Height: -1},
+	}
+
+	diff := DiffHistory("sh", previous, history)
+	assert.Equal(t, []*GetMempoolResult{{Hash: "e", Height: -1}}, diff.Added)
+	assert.Equal(t, []*GetMempoolResult{{Hash: "d", Height: 0}}, diff.Removed)
+	assert.Equal(t, []*GetMempoolResult{{Hash: "c", Height: 12}}, diff.Confirmed)
+	assert.Equal(t, []*GetMempoolResult{{Hash: "b", Height: 0}}, diff.Unconfirmed)
+	assert.Equal(t, ScripthashStatus(history), diff.Status)
+
+	assert.True(t, DiffHistory("sh", history, history).Empty())
+}
@@ -33,11 +33,32 @@ func (s *Client) SubscribeHeaders(ctx context.Context) (<-chan *SubscribeHeaders
 		return nil, err
 	}
 
-	respChan := make(chan *SubscribeHeadersResult, 1)
+	respChan := make(chan *SubscribeHeadersResult, s.notifBuffer())
 	respChan <- resp.Result
+	notify := func(result *SubscribeHeadersResult) {
+		select {
+		case respChan <- result:
+		default:
+			s.notificationDropped("blockchain.headers.subscribe", nil)
+		}
+	}
+
+	s.onReconnect(func(ctx context.Context) error {
+		var resp SubscribeHeadersResp
+
+		err := s.request(ctx, "blockchain.headers.subscribe", []interface{}{}, &resp)
+		if err != nil {
+			return err
+		}
+
+		notify(resp.Result)
 
+		return nil
+	})
+
+	pushes := s.listenPush("blockchain.headers.subscribe")
 	go func() {
-		for msg := range s.listenPush("blockchain.headers.subscribe") {
+		for msg := range pushes {
 			if msg.err != nil {
 				return
 			}
@@ -50,7 +71,7 @@ func (s *Client) SubscribeHeaders(ctx context.Context) (<-chan *SubscribeHeaders
 			}
 
 			for _, param := range resp.Params {
-				respChan <- param
+				notify(param)
 			}
 		}
 	}()
@@ -66,6 +87,17 @@ type ScripthashSubscription struct {
 	subscribedSH  []string
 	scripthashMap map[string]string
 
+	// pending holds the latest undelivered status of each scripthash, delivered in the
+	// order of pendingOrder by deliver().
+	pending      map[string]string
+	pendingOrder []string
+	pendingLock  sync.Mutex
+	wake         chan struct{}
+
+	// resubscriber is the id of the replay of the subscription, registered while it
+	// holds scripthashes.
+	resubscriber uint64
+
 	lock sync.RWMutex
 }
 
@@ -78,12 +110,17 @@ type SubscribeNotif struct {
 func (s *Client) SubscribeScripthash() (*ScripthashSubscription, <-chan *SubscribeNotif) {
 	sub := &ScripthashSubscription{
 		server:        s,
-		notifChan:     make(chan *SubscribeNotif, 1),
+		notifChan:     make(chan *SubscribeNotif, s.notifBuffer()),
 		scripthashMap: make(map[string]string),
+		pending:       make(map[string]string),
+		wake:          make(chan struct{}, 1),
 	}
 
+	go sub.deliver()
+
+	pushes := s.listenPush("blockchain.scripthash.subscribe")
 	go func() {
-		for msg := range s.listenPush("blockchain.scripthash.subscribe") {
+		for msg := range pushes {
 			if msg.err != nil {
 				return
 			}
@@ -95,22 +132,78 @@ func (s *Client) SubscribeScripthash() (*ScripthashSubscription, <-chan *Subscri
 				return
 			}
 
-			sub.lock.Lock()
+			sub.lock.RLock()
 			for _, a := range sub.subscribedSH {
 				if a == resp.Params[0] {
-					sub.notifChan <- &resp
+					sub.notify(&resp)
 					break
 				}
 			}
-			sub.lock.Unlock()
+			sub.lock.RUnlock()
 		}
 	}()
 
 	return sub, sub.notifChan
 }
 
-// Add ...
-func (sub *ScripthashSubscription) Add(ctx context.Context, scripthash string, address ...string) error {
+// notify queues notif without blocking. A status not yet delivered is replaced by the
+// newer status of the same scripthash, so that no scripthash misses its latest status.
+func (sub *ScripthashSubscription) notify(notif *SubscribeNotif) {
+	scripthash, status := notif.Params[0], notif.Params[1]
+
+	sub.pendingLock.Lock()
+	if _, ok := sub.pending[scripthash]; !ok {
+		sub.pendingOrder = append(sub.pendingOrder, scripthash)
+	}
+	sub.pending[scripthash] = status
+	sub.pendingLock.Unlock()
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the pending statuses to the notification channel until the client shuts down.
+func (sub *ScripthashSubscription) deliver() {
+	for {
+		select {
+		case <-sub.server.quit:
+			return
+		case <-sub.wake:
+		}
+
+		for {
+			sub.pendingLock.Lock()
+			if len(sub.pendingOrder) == 0 {
+				sub.pendingLock.Unlock()
+				break
+			}
+			scripthash := sub.pendingOrder[0]
+			sub.pendingOrder = sub.pendingOrder[1:]
+			status := sub.pending[scripthash]
+			delete(sub.pending, scripthash)
+			sub.pendingLock.Unlock()
+
+			select {
+			case sub.notifChan <- &SubscribeNotif{[2]string{scripthash, status}}:
+			case <-sub.server.quit:
+				return
+			}
+		}
+	}
+}
+
+// removed stops replaying the subscription once its last scripthash has been removed.
+// It must be called with the lock held.
+func (sub *ScripthashSubscription) removed() {
+	if len(sub.subscribedSH) == 0 {
+		sub.server.removeResubscriber(sub.resubscriber)
+		sub.resubscriber = 0
+	}
+}
+
+func (sub *ScripthashSubscription) subscribe(ctx context.Context, scripthash string) error {
 	var resp basicResp
 
 	err := sub.server.request(ctx, "blockchain.scripthash.subscribe", []interface{}{scripthash}, &resp)
@@ -119,7 +212,17 @@ func (sub *ScripthashSubscription) Add(ctx context.Context, scripthash string, a
 	}
 
 	if len(resp.Result) > 0 {
-		sub.notifChan <- &SubscribeNotif{[2]string{scripthash, resp.Result}}
+		sub.notify(&SubscribeNotif{[2]string{scripthash, resp.Result}})
+	}
+
+	return nil
+}
+
+// Add ...
+func (sub *ScripthashSubscription) Add(ctx context.Context, scripthash string, address ...string) error {
+	err := sub.subscribe(ctx, scripthash)
+	if err != nil {
+		return err
 	}
 
 	sub.lock.Lock()
@@ -127,6 +230,9 @@ func (sub *ScripthashSubscription) Add(ctx context.Context, scripthash string, a
 	if len(address) > 0 {
 		sub.scripthashMap[scripthash] = address[0]
 	}
+	if sub.resubscriber == 0 {
+		sub.resubscriber = sub.server.onReconnect(sub.Resubscribe)
+	}
 	sub.lock.Unlock()
 
 	return nil
@@ -134,7 +240,10 @@ func (sub *ScripthashSubscription) Add(ctx context.Context, scripthash string, a
 
 // GetAddress ...
 func (sub *ScripthashSubscription) GetAddress(scripthash string) (string, error) {
+	sub.lock.RLock()
 	address, ok := sub.scripthashMap[scripthash]
+	sub.lock.RUnlock()
+
 	if ok {
 		return address, nil
 	}
@@ -144,6 +253,19 @@ func (sub *ScripthashSubscription) GetAddress(scripthash string) (string, error)
 
 // GetScripthash ...
 func (sub *ScripthashSubscription) GetScripthash(address string) (string, error) {
+	sub.lock.RLock()
+	defer sub.lock.RUnlock()
+
+	scripthash, found := sub.scripthashOf(address)
+	if found {
+		return scripthash, nil
+	}
+
+	return "", errors.New("address not found in map")
+}
+
+// scripthashOf returns the scripthash recorded for address. It must be called with the lock held.
+func (sub *ScripthashSubscription) scripthashOf(address string) (string, bool) {
 	var found bool
 	var scripthash string
 
@@ -154,11 +276,7 @@ func (sub *ScripthashSubscription) GetScripthash(address string) (string, error)
 		}
 	}
 
-	if found {
-		return scripthash, nil
-	}
-
-	return "", errors.New("address not found in map")
+	return scripthash, found
 }
 
 // GetChannel ...
@@ -168,11 +286,13 @@ func (sub *ScripthashSubscription) GetChannel() <-chan *SubscribeNotif {
 
 // Remove ...
 func (sub *ScripthashSubscription) Remove(scripthash string) error {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
 	for i, v := range sub.subscribedSH {
 		if v == scripthash {
-			sub.lock.Lock()
 			sub.subscribedSH = append(sub.subscribedSH[:i], sub.subscribedSH[i+1:]...)
-			sub.lock.Unlock()
+			sub.removed()
 			return nil
 		}
 	}
@@ -182,17 +302,19 @@ func (sub *ScripthashSubscription) Remove(scripthash string) error {
 
 // RemoveAddress ...
 func (sub *ScripthashSubscription) RemoveAddress(address string) error {
-	scripthash, err := sub.GetScripthash(address)
-	if err != nil {
-		return err
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	scripthash, found := sub.scripthashOf(address)
+	if !found {
+		return errors.New("address not found in map")
 	}
 
 	for i, v := range sub.subscribedSH {
 		if v == scripthash {
-			sub.lock.Lock()
 			sub.subscribedSH = append(sub.subscribedSH[:i], sub.subscribedSH[i+1:]...)
 			delete(sub.scripthashMap, scripthash)
-			sub.lock.Unlock()
+			sub.removed()
 			return nil
 		}
 	}
@@ -200,10 +322,15 @@ func (sub *ScripthashSubscription) RemoveAddress(address string) error {
 	return errors.New("scripthash not found")
 }
 
-// Resubscribe ...
+// Resubscribe subscribes again to every scripthash of the subscription, for instance
+// after the connection to the remote server has been lost.
 func (sub *ScripthashSubscription) Resubscribe(ctx context.Context) error {
-	for _, v := range sub.subscribedSH {
-		err := sub.Add(ctx, v)
+	sub.lock.RLock()
+	subscribed := append([]string(nil), sub.subscribedSH...)
+	sub.lock.RUnlock()
+
+	for _, v := range subscribed {
+		err := sub.subscribe(ctx, v)
 		if err != nil {
 			return err
 		}
@@ -222,13 +349,36 @@ func (s *Client) SubscribeMasternode(ctx context.Context, collateral string) (<-
 		return nil, err
 	}
 
-	respChan := make(chan string, 1)
+	respChan := make(chan string, s.notifBuffer())
 	if len(resp.Result) > 0 {
 		respChan <- resp.Result
 	}
+	notify := func(status string) {
+		select {
+		case respChan <- status:
+		default:
+			s.notificationDropped("blockchain.masternode.subscribe", nil)
+		}
+	}
+
+	s.onReconnect(func(ctx context.Context) error {
+		var resp basicResp
+
+		err := s.request(ctx, "blockchain.masternode.subscribe", []interface{}{collateral}, &resp)
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Result) > 0 {
+			notify(resp.Result)
+		}
+
+		return nil
+	})
 
+	pushes := s.listenPush("blockchain.masternode.subscribe")
 	go func() {
-		for msg := range s.listenPush("blockchain.masternode.subscribe") {
+		for msg := range pushes {
 			if msg.err != nil {
 				return
 			}
@@ -241,7 +391,7 @@ func (s *Client) SubscribeMasternode(ctx context.Context, collateral string) (<-
 			}
 
 			for _, param := range resp.Params {
-				respChan <- param
+				notify(param)
 			}
 		}
 	}()
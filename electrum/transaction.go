@@ -1,14 +1,25 @@
 package electrum
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcutil"
+)
 
 // BroadcastTransaction sends a raw transaction to the remote server to
-// be broadcasted on the server network.
+// be broadcasted on the server network. If the node rejects it, which the remote server
+// reports as a bad request, a *TxRejectedError holding the reject reason is returned.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-transaction-broadcast
 func (s *Client) BroadcastTransaction(ctx context.Context, rawTx string) (string, error) {
 	resp := &basicResp{}
 	err := s.request(ctx, "blockchain.transaction.broadcast", []interface{}{rawTx}, &resp)
 	if err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Is(ErrTxRejected) {
+			return "", &TxRejectedError{Reason: rejectReason(rpcErr.Message), Err: rpcErr}
+		}
 		return "", err
 	}
 
@@ -33,7 +44,7 @@ type GetTransactionResult struct {
 	Version       uint32               `json:"version"`
 	Vin           []Vin                `json:"vin"`
 	Vout          []Vout               `json:"vout"`
-	Merkle        GetMerkleProofResult `json:"merkle,omitempty"` // For protocol v1.5 and up.
+	Merkle        GetMerkleProofResult `json:"merkle,omitempty"` // For protocol v1.5 and up, see GetTransaction().
 }
 
 // Vin represents the input side of a transaction.
@@ -53,11 +64,54 @@ type ScriptSig struct {
 
 // Vout represents the output side of a transaction.
 type Vout struct {
+	N            uint32         `json:"n"`
+	ScriptPubkey ScriptPubkey   `json:"scriptpubkey"`
+	Value        btcutil.Amount `json:"value"`
+}
+
+type voutJSON struct {
 	N            uint32       `json:"n"`
 	ScriptPubkey ScriptPubkey `json:"scriptpubkey"`
 	Value        float64      `json:"value"`
 }
 
+// UnmarshalJSON decodes the output value sent in BTC by the remote server into satoshis.
+func (v *Vout) UnmarshalJSON(data []byte) error {
+	var raw voutJSON
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	value, err := btcutil.NewAmount(raw.Value)
+	if err != nil {
+		return err
+	}
+
+	v.N = raw.N
+	v.ScriptPubkey = raw.ScriptPubkey
+	v.Value = value
+
+	return nil
+}
+
+// MarshalJSON encodes the output value in BTC, the way the remote server sends it.
+func (v Vout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(voutJSON{
+		N:            v.N,
+		ScriptPubkey: v.ScriptPubkey,
+		Value:        v.Value.ToBTC(),
+	})
+}
+
+// ValueBTC returns the output value in BTC.
+//
+// Deprecated: use Value, which holds the exact amount in satoshis.
+func (v Vout) ValueBTC() float64 {
+	return v.Value.ToBTC()
+}
+
 // ScriptPubkey represents the script of that transaction output.
 type ScriptPubkey struct {
 	Addresses []string `json:"addresses,omitempty"`
@@ -68,6 +122,7 @@ type ScriptPubkey struct {
 }
 
 // GetTransaction gets the detailed information for a transaction.
+// The Merkle field is only set when protocol v1.5 or up has been negotiated, and ignored otherwise.
 // https://electrumx.readthedocs.io/en/latest/protocol-methods.html#blockchain-transaction-get
 func (s *Client) GetTransaction(ctx context.Context, txHash string) (*GetTransactionResult, error) {
 	var resp GetTransactionResp
@@ -77,6 +132,10 @@ func (s *Client) GetTransaction(ctx context.Context, txHash string) (*GetTransac
 		return nil, err
 	}
 
+	if resp.Result != nil && s.checkFeature("transaction.merkle") != nil {
+		resp.Result.Merkle = GetMerkleProofResult{}
+	}
+
 	return resp.Result, nil
 }
 
@@ -4,9 +4,7 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
-	"log"
 	"net"
-	"time"
 )
 
 // TCPTransport store information about the TCP transport.
@@ -16,46 +14,75 @@ type TCPTransport struct {
 	errors    chan error
 }
 
-// NewTCPTransport opens a new TCP connection to the remote server.
-func NewTCPTransport(ctx context.Context, addr string) (*TCPTransport, error) {
-	var d net.Dialer
-
-	conn, err := d.DialContext(ctx, "tcp", addr)
+// NewTCPTransport opens a new TCP connection to the remote server, through dialer when given.
+func NewTCPTransport(ctx context.Context, addr string, dialer ...Dialer) (*TCPTransport, error) {
+	conn, err := dialTCP(ctx, addr, defaultDialer(dialer))
 	if err != nil {
 		return nil, err
 	}
 
-	tcp := &TCPTransport{
-		conn:      conn,
-		responses: make(chan []byte),
-		errors:    make(chan error),
+	return newTCPTransport(conn, 0), nil
+}
+
+// NewSSLTransport opens a new SSL connection to the remote server, through dialer when given.
+func NewSSLTransport(ctx context.Context, addr string, config *tls.Config, dialer ...Dialer) (*TCPTransport, error) {
+	conn, err := dialSSL(ctx, addr, config, defaultDialer(dialer))
+	if err != nil {
+		return nil, err
 	}
 
-	go tcp.listen()
+	return newTCPTransport(conn, 0), nil
+}
 
-	return tcp, nil
+func dialTCP(ctx context.Context, addr string, dialer Dialer) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	return dialer.DialContext(ctx, "tcp", addr)
 }
 
-// NewSSLTransport opens a new SSL connection to the remote server.
-func NewSSLTransport(ctx context.Context, addr string, config *tls.Config) (*TCPTransport, error) {
-	dialer := tls.Dialer{
-		NetDialer: &net.Dialer{},
-		Config:    config,
+func dialSSL(ctx context.Context, addr string, config *tls.Config, dialer Dialer) (net.Conn, error) {
+	rawConn, err := dialTCP(ctx, addr, dialer)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		config = &tls.Config{}
 	}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			_ = rawConn.Close()
+			return nil, err
+		}
+
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	conn := tls.Client(rawConn, config)
+	err = conn.HandshakeContext(ctx)
 	if err != nil {
+		_ = rawConn.Close()
 		return nil, err
 	}
 
+	return conn, nil
+}
+
+// newTCPTransport starts a transport over conn, queuing up to buffer received messages.
+func newTCPTransport(conn net.Conn, buffer int) *TCPTransport {
 	tcp := &TCPTransport{
 		conn:      conn,
-		responses: make(chan []byte),
+		responses: make(chan []byte, buffer),
 		errors:    make(chan error),
 	}
 
 	go tcp.listen()
 
-	return tcp, nil
+	return tcp
 }
 
 func (t *TCPTransport) listen() {
@@ -68,9 +95,6 @@ func (t *TCPTransport) listen() {
 			t.errors <- err
 			break
 		}
-		if DebugMode {
-			log.Printf("%s [debug] %s -> %s", time.Now().Format("2006-01-02 15:04:05"), t.conn.RemoteAddr(), line)
-		}
 
 		t.responses <- line
 	}
@@ -78,10 +102,6 @@ func (t *TCPTransport) listen() {
 
 // SendMessage sends a message to the remote server through the TCP transport.
 func (t *TCPTransport) SendMessage(body []byte) error {
-	if DebugMode {
-		log.Printf("%s [debug] %s <- %s", time.Now().Format("2006-01-02 15:04:05"), t.conn.RemoteAddr(), body)
-	}
-
 	_, err := t.conn.Write(body)
 	return err
 }
@@ -96,6 +116,11 @@ func (t *TCPTransport) Errors() <-chan error {
 	return t.errors
 }
 
+// RemoteAddr returns the address of the remote server.
+func (t *TCPTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
 func (t *TCPTransport) Close() error {
 	return t.conn.Close()
 }
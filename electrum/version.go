@@ -0,0 +1,149 @@
+package electrum
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersionMin is the oldest protocol version the client negotiates with the remote server.
+const ProtocolVersionMin = "1.4"
+
+// protocolFeature describes the protocol versions a call is available in.
+type protocolFeature struct {
+	since   string
+	removed string
+}
+
+// protocolFeatures lists the calls that are not available in every protocol version the
+// client may negotiate. Every call is available from ProtocolVersionMin to ProtocolVersion,
+// these only apply when WithProtocolVersions() raises the maximum version.
+var protocolFeatures = map[string]protocolFeature{
+	"blockchain.relayfee": {removed: "1.6"},
+
+	// Merkle proof in the verbose result of blockchain.transaction.get.
+	"transaction.merkle": {since: "1.5"},
+}
+
+// compareVersions compares two dotted protocol versions, missing components being 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// versionParams returns the client name and protocol version range sent to the remote server.
+// Versions below ProtocolVersionMin are raised to it.
+func (s *Client) versionParams() []interface{} {
+	clientName := ClientVersion
+	if s.clientName != "" {
+		clientName = s.clientName
+	}
+
+	min, max := ProtocolVersionMin, ProtocolVersion
+	if s.protocolMin != "" {
+		min = s.protocolMin
+	}
+	if s.protocolMax != "" {
+		max = s.protocolMax
+	}
+	if compareVersions(min, ProtocolVersionMin) < 0 {
+		min = ProtocolVersionMin
+	}
+	if compareVersions(max, min) < 0 {
+		max = min
+	}
+
+	if min == max {
+		return []interface{}{clientName, max}
+	}
+
+	return []interface{}{clientName, []string{min, max}}
+}
+
+// negotiate sends the protocol version range to the remote server, and stores the agreed version.
+func (s *Client) negotiate(ctx context.Context) (serverVer, protocolVer string, err error) {
+	var resp ServerVersionResp
+
+	err = s.request(ctx, "server.version", s.versionParams(), &resp)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.setVersion(resp.Result[0], resp.Result[1])
+
+	return resp.Result[0], resp.Result[1], nil
+}
+
+func (s *Client) setVersion(serverVer, protocolVer string) {
+	s.versionLock.Lock()
+	s.serverVersion = serverVer
+	s.negotiatedVersion = protocolVer
+	s.versionLock.Unlock()
+}
+
+// NegotiatedVersion returns the protocol version agreed with the remote server, or an empty
+// string before the negotiation.
+func (s *Client) NegotiatedVersion() string {
+	s.versionLock.RLock()
+	defer s.versionLock.RUnlock()
+
+	return s.negotiatedVersion
+}
+
+// SupportsProtocol reports whether the protocol version agreed with the remote server is at
+// least version. Before the negotiation, every version is assumed to be supported.
+func (s *Client) SupportsProtocol(version string) bool {
+	negotiated := s.NegotiatedVersion()
+
+	return negotiated == "" || compareVersions(negotiated, version) >= 0
+}
+
+// checkFeature returns ErrNotImplemented if feature is not yet available in the protocol
+// version agreed with the remote server, or ErrDeprecated if it has been removed.
+func (s *Client) checkFeature(feature string) error {
+	negotiated := s.NegotiatedVersion()
+	if negotiated == "" {
+		return nil
+	}
+
+	f := protocolFeatures[feature]
+	if f.since != "" && compareVersions(negotiated, f.since) < 0 {
+		return fmt.Errorf("%w: %s requires protocol %s, negotiated %s", ErrNotImplemented, feature, f.since, negotiated)
+	}
+	if f.removed != "" && compareVersions(negotiated, f.removed) >= 0 {
+		return fmt.Errorf("%w: %s removed in protocol %s, negotiated %s", ErrDeprecated, feature, f.removed, negotiated)
+	}
+
+	return nil
+}
+
+// start runs the client over its transport, and negotiates the protocol version.
+func (s *Client) start(ctx context.Context) error {
+	go s.listen()
+
+	_, _, err := s.negotiate(ctx)
+	if err != nil {
+		s.Shutdown()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package electrum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("1.4", "1.4.0"))
+	assert.Equal(t, -1, compareVersions("1.4", "1.4.2"))
+	assert.Equal(t, 1, compareVersions("1.10", "1.9"))
+	assert.Equal(t, -1, compareVersions("1.2", "1.4"))
+}
+
+func TestCheckFeature(t *testing.T) {
+	c := &Client{}
+	assert.NoError(t, c.checkFeature("blockchain.relayfee"))
+
+	c.setVersion("ElectrumX 1.16.0", "1.4")
+	assert.NoError(t, c.checkFeature("blockchain.relayfee"))
+	assert.ErrorIs(t, c.checkFeature("transaction.merkle"), ErrNotImplemented)
+	assert.False(t, c.SupportsProtocol("1.5"))
+
+	c.setVersion("ElectrumX 1.16.0", "1.6")
+	assert.ErrorIs(t, c.checkFeature("blockchain.relayfee"), ErrDeprecated)
+	assert.True(t, c.SupportsProtocol("1.5"))
+}
+
+func TestVersionParams(t *testing.T) {
+	c := &Client{}
+	assert.Equal(t, []interface{}{ClientVersion, ProtocolVersion}, c.versionParams())
+
+	c.protocolMin, c.protocolMax = "1.4", "1.6"
+	assert.Equal(t, []interface{}{ClientVersion, []string{"1.4", "1.6"}}, c.versionParams())
+
+	// Versions below ProtocolVersionMin are raised to it.
+	c.protocolMin, c.protocolMax = "1.2", "1.6"
+	assert.Equal(t, []interface{}{ClientVersion, []string{ProtocolVersionMin, "1.6"}}, c.versionParams())
+
+	c.protocolMin, c.protocolMax = "1.2", "1.3"
+	assert.Equal(t, []interface{}{ClientVersion, ProtocolVersionMin}, c.versionParams())
+}
@@ -0,0 +1,140 @@
+package electrum
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketTransport store information about the WebSocket transport.
+type WebSocketTransport struct {
+	conn      *websocket.Conn
+	responses chan []byte
+	errors    chan error
+
+	writeLock sync.Mutex
+}
+
+// NewWebSocketTransport opens a new WebSocket connection to the remote server at url,
+// using the ws:// or wss:// scheme. config is used for wss:// connections and may be nil.
+// When dialer is given, the connection is opened through it instead of the HTTP proxy
+// from the environment.
+func NewWebSocketTransport(ctx context.Context, url string, config *tls.Config, dialer ...Dialer) (*WebSocketTransport, error) {
+	var netDialer Dialer
+	if len(dialer) > 0 {
+		netDialer = dialer[0]
+	}
+
+	conn, err := dialWebSocket(ctx, url, config, netDialer)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWebSocketTransport(conn, 0), nil
+}
+
+func dialWebSocket(ctx context.Context, url string, config *tls.Config, dialer Dialer) (*websocket.Conn, error) {
+	wsDialer := websocket.Dialer{
+		Proxy:           websocket.DefaultDialer.Proxy,
+		TLSClientConfig: config,
+	}
+	if dialer != nil {
+		wsDialer.Proxy = nil
+		wsDialer.NetDialContext = dialer.DialContext
+	}
+
+	conn, _, err := wsDialer.DialContext(ctx, url, nil)
+
+	return conn, err
+}
+
+// newWebSocketTransport starts a transport over conn, queuing up to buffer received messages.
+func newWebSocketTransport(conn *websocket.Conn, buffer int) *WebSocketTransport {
+	ws := &WebSocketTransport{
+		conn:      conn,
+		responses: make(chan []byte, buffer),
+		errors:    make(chan error),
+	}
+
+	go ws.listen()
+
+	return ws
+}
+
+func (t *WebSocketTransport) listen() {
+	defer t.conn.Close()
+
+	for {
+		_, msg, err := t.conn.ReadMessage()
+		if err != nil {
+			t.errors <- err
+			break
+		}
+
+		// Every message should hold a single JSON-RPC message, but tolerate servers
+		// sending newline delimited messages.
+		for _, line := range bytes.Split(msg, []byte{nl}) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				t.responses <- line
+			}
+		}
+	}
+}
+
+// SendMessage sends a message to the remote server through the WebSocket transport,
+// as a single text message.
+func (t *WebSocketTransport) SendMessage(body []byte) error {
+	t.writeLock.Lock()
+	defer t.writeLock.Unlock()
+
+	return t.conn.WriteMessage(websocket.TextMessage, bytes.TrimRight(body, string(nl)))
+}
+
+// Responses returns chan to WebSocket transport responses.
+func (t *WebSocketTransport) Responses() <-chan []byte {
+	return t.responses
+}
+
+// Errors returns chan to WebSocket transport errors.
+func (t *WebSocketTransport) Errors() <-chan error {
+	return t.errors
+}
+
+// RemoteAddr returns the address of the remote server.
+func (t *WebSocketTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+func (t *WebSocketTransport) Close() error {
+	return t.conn.Close()
+}
+
+// WebSocketDialer returns a TransportDialer opening WebSocket connections to url, through
+// dialer when given.
+func WebSocketDialer(url string, config *tls.Config, dialer ...Dialer) TransportDialer {
+	return func(ctx context.Context) (Transport, error) {
+		return NewWebSocketTransport(ctx, url, config, dialer...)
+	}
+}
+
+// NewClientWS initialize a new client for remote server and connects to the remote server
+// using WebSocket, with url in the ws://host:port or wss://host:port form, then negotiates
+// the protocol version, see ServerVersion().
+func NewClientWS(ctx context.Context, url string, config *tls.Config, dialer ...Dialer) (*Client, error) {
+	transport, err := NewWebSocketTransport(ctx, url, config, dialer...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newClient(transport, &clientOptions{})
+	err = c.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,128 @@
+package electrum_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/checksum0/go-electrum/electrum"
+	"github.com/checksum0/go-electrum/electrum/electrumtest"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveWebSocket exposes server over WebSocket, each message holding a single JSON-RPC
+// message. The returned channel is closed once the client has closed its connection.
+func serveWebSocket(t *testing.T, server *electrumtest.Server) (string, <-chan struct{}) {
+	closed := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		transport, err := server.Transport()
+		if err != nil {
+			return
+		}
+		defer transport.Close()
+
+		go func() {
+			for {
+				select {
+				case line := <-transport.Responses():
+					if conn.WriteMessage(websocket.TextMessage, line) != nil {
+						return
+					}
+				case <-transport.Errors():
+					conn.Close()
+					return
+				}
+			}
+		}()
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				close(closed)
+				return
+			}
+
+			if transport.SendMessage(msg) != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	return "ws" + strings.TrimPrefix(ts.URL, "http"), closed
+}
+
+func TestWebSocketClient(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	server.SetResult("server.banner", "banner")
+	server.SetResult("blockchain.headers.subscribe", &electrum.SubscribeHeadersResult{Height: 100, Hex: "00"})
+
+	url, _ := serveWebSocket(t, server)
+
+	client, err := electrum.NewClientWS(context.Background(), url, nil)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	banner, err := client.ServerBanner(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "banner", banner)
+
+	headers, err := client.SubscribeHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(100), (<-headers).Height)
+
+	require.NoError(t, server.Notify("blockchain.headers.subscribe", &electrum.SubscribeHeadersResult{Height: 101, Hex: "01"}))
+
+	select {
+	case header := <-headers:
+		assert.Equal(t, int32(101), header.Height)
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+	}
+
+	// The connection closed by the remote server is reported to the client.
+	server.Disconnect()
+
+	select {
+	case err := <-client.Error:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("connection loss not reported")
+	}
+
+	_, err = client.ServerBanner(context.Background())
+	assert.Error(t, err)
+}
+
+func TestWebSocketClientShutdown(t *testing.T) {
+	server := electrumtest.NewServer()
+	defer server.Close()
+
+	url, closed := serveWebSocket(t, server)
+
+	client, err := electrum.NewClientWS(context.Background(), url, nil)
+	require.NoError(t, err)
+
+	client.Shutdown()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed")
+	}
+}
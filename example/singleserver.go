@@ -9,7 +9,8 @@ import (
 )
 
 func main() {
-	client, err := electrum.NewClientTCP(context.Background(), "bch.imaginary.cash:50001")
+	client, err := electrum.NewClient(context.Background(), "tcp://bch.imaginary.cash:50001",
+		electrum.WithKeepAlive(60*time.Second))
 
 	if err != nil {
 		log.Fatal(err)
@@ -22,11 +23,8 @@ func main() {
 	log.Printf("Server version: %s [Protocol %s]", serverVer, protocolVer)
 
 	go func() {
-		for {
-			if err := client.Ping(context.Background()); err != nil {
-				log.Fatal(err)
-			}
-			time.Sleep(60 * time.Second)
+		for err := range client.Error {
+			log.Fatal(err)
 		}
 	}()
 }